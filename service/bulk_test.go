@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateWhere(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "update_where")
+
+	done := true
+	filter := todoService.Filter()
+	filter.Category().Equal("work")
+	affected, err := todoService.UpdateWhere(ctx, filter, service.TodoPatch{Done: &done}, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, affected)
+
+	filter = todoService.Filter()
+	filter.Done().Equal(true)
+	assert.Equal(t, []any{2, 4, 5, 6, 8}, findIDs(t, ctx, todoService, filter))
+
+	title := "renamed"
+	category := int64(3)
+	description := sql.NullString{}
+	filter = todoService.Filter()
+	filter.ID().Between(1, 2)
+	affected, err = todoService.UpdateWhere(ctx, filter, service.TodoPatch{
+		Title: &title, Description: &description, CategoryID: &category,
+	}, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, affected)
+	todo, err := todoService.Get(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, todo.CompletedAt.Valid)
+	todo.CreatedAt, todo.UpdatedAt, todo.CompletedAt = time.Time{}, time.Time{}, sql.NullTime{}
+	assert.Equal(t, service.Todo{
+		ID: 2, Title: "renamed", Category: service.TodoCategory{ID: 3, Name: "errand"}, Done: true,
+	}, todo)
+
+	_, err = todoService.UpdateWhere(ctx, todoService.Filter(), service.TodoPatch{Done: &done}, false)
+	assert.ErrorIs(t, err, service.ErrEmptyFilter)
+	_, err = todoService.UpdateWhere(ctx, filter, service.TodoPatch{}, false)
+	assert.ErrorIs(t, err, service.ErrEmptyPatch)
+
+	affected, err = todoService.UpdateWhere(ctx, nil, service.TodoPatch{Done: &done}, true)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 9, affected)
+}
+
+func TestDeleteWhere(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "delete_where")
+
+	filter := todoService.Filter()
+	filter.Or(filter.Category().Equal("home"), filter.Description().IsNotNull())
+	affected, err := todoService.DeleteWhere(ctx, filter, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 6, affected)
+	assert.Equal(t, []any{2, 5, 8}, findIDs(t, ctx, todoService, nil))
+
+	_, err = todoService.DeleteWhere(ctx, nil, false)
+	assert.ErrorIs(t, err, service.ErrEmptyFilter)
+	for name, build := range map[string]func(service.TodoFilter){
+		"empty or":           func(f service.TodoFilter) { f.Or() },
+		"has none of no tag": func(f service.TodoFilter) { f.Tags().HasNone(nil) },
+		"has all of no tag":  func(f service.TodoFilter) { f.Tags().HasAll([]string{}) },
+		"empty search":       func(f service.TodoFilter) { f.Search("  ") },
+		"not empty search":   func(f service.TodoFilter) { f.Not(f.Search("")) },
+		"nested": func(f service.TodoFilter) {
+			f.And(f.Or(f.Search(""), f.Tags().HasNone(nil)), f.Tags().HasAll(nil))
+		},
+	} {
+		filter = todoService.Filter()
+		build(filter)
+		_, err = todoService.DeleteWhere(ctx, filter, false)
+		assert.ErrorIs(t, err, service.ErrEmptyFilter, name)
+		done := true
+		_, err = todoService.UpdateWhere(ctx, filter, service.TodoPatch{Done: &done}, false)
+		assert.ErrorIs(t, err, service.ErrEmptyFilter, name)
+	}
+	assert.Equal(t, []any{2, 5, 8}, findIDs(t, ctx, todoService, nil))
+
+	affected, err = todoService.DeleteWhere(ctx, nil, true)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, affected)
+	assert.Equal(t, []any{}, findIDs(t, ctx, todoService, nil))
+}
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelete(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "delete")
+
+	err := todoService.Delete(ctx, []int64{2, 4, 42})
+	assert.ErrorIs(t, err, service.ErrNoData)
+	assert.EqualError(t, err, "no data: todo 42")
+	assert.Equal(t, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}, findIDs(t, ctx, todoService, nil), "rolled back")
+
+	assert.NoError(t, todoService.Delete(ctx, []int64{2, 4}))
+	assert.Equal(t, []any{1, 3, 5, 6, 7, 8, 9}, findIDs(t, ctx, todoService, nil))
+	_, err = todoService.Get(ctx, 2)
+	assert.ErrorIs(t, err, service.ErrNoData)
+}
+
+func TestUpdateCategory(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "update_category")
+
+	assert.NoError(t, todoService.UpdateCategory(ctx, []service.TodoCategory{{ID: 2, Name: "office"}}))
+	todo, err := todoService.Get(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, service.TodoCategory{ID: 2, Name: "office"}, todo.Category)
+
+	err = todoService.UpdateCategory(ctx, []service.TodoCategory{{ID: 1, Name: "house"}, {ID: 42, Name: "none"}})
+	assert.ErrorIs(t, err, service.ErrNoData)
+	todo, err = todoService.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "home", todo.Category.Name, "rolled back")
+}
+
+func TestDeleteCategory(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "delete_category")
+
+	t.Run("restrict", func(t *testing.T) {
+		err := todoService.DeleteCategory(ctx, []int64{1, 2}, service.DeleteRestrict, 0)
+		var inUse *service.CategoryInUseError
+		if assert.True(t, errors.As(err, &inUse), "got %v", err) {
+			assert.EqualValues(t, 6, inUse.Todos)
+		}
+		assert.ErrorIs(t, err, service.ErrCategoryInUse)
+		assert.EqualError(t, err, "category in use: 6 todos")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := todoService.DeleteCategory(ctx, []int64{3, 42}, service.DeleteCascade, 0)
+		assert.ErrorIs(t, err, service.ErrNoData)
+		assert.EqualError(t, err, "no data: category 42")
+		assert.Equal(t, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}, findIDs(t, ctx, todoService, nil), "rolled back")
+	})
+
+	t.Run("reassign", func(t *testing.T) {
+		err := todoService.DeleteCategory(ctx, []int64{1}, service.DeleteReassign, 1)
+		assert.Error(t, err)
+		err = todoService.DeleteCategory(ctx, []int64{1}, service.DeleteReassign, 42)
+		assert.ErrorIs(t, err, service.ErrNoData)
+
+		assert.NoError(t, todoService.DeleteCategory(ctx, []int64{1}, service.DeleteReassign, 3))
+		filter := todoService.Filter()
+		filter.Category().Equal("errand")
+		assert.Equal(t, []any{1, 3, 4, 6, 7, 9}, findIDs(t, ctx, todoService, filter))
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		assert.NoError(t, todoService.DeleteCategory(ctx, []int64{3}, service.DeleteCascade, 0))
+		assert.Equal(t, []any{2, 5, 8}, findIDs(t, ctx, todoService, nil))
+	})
+
+	t.Run("restrict empty", func(t *testing.T) {
+		ids, err := todoService.CreateCategory(ctx, []service.TodoCategory{{Name: "empty"}})
+		assert.NoError(t, err)
+		assert.NoError(t, todoService.DeleteCategory(ctx, ids, service.DeleteRestrict, 0))
+		err = todoService.UpdateCategory(ctx, []service.TodoCategory{{ID: ids[0], Name: "gone"}})
+		assert.ErrorIs(t, err, service.ErrNoData)
+	})
+}
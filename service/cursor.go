@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Cursor is the position after the last todo of a page: the sort it was
+// produced for and that todo's value for each sort key, id last.
+type Cursor struct {
+	Sort   []string `json:"s"`
+	Values []any    `json:"v"`
+}
+
+// Page is one page of FindPage results. Next is empty on the last page.
+type Page struct {
+	Todos []Todo `json:"todos"`
+	Next  string `json:"next,omitempty"`
+}
+
+// SignCursor encodes c as an opaque string carrying an HMAC-SHA256 of its
+// content under key.
+func SignCursor(key []byte, c Cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ParseCursor verifies and decodes a cursor made by SignCursor with the same
+// key. Numbers in Values decode as json.Number. Any malformed or altered
+// cursor fails with ErrInvalidCursor.
+func ParseCursor(key []byte, s string) (Cursor, error) {
+	var c Cursor
+	enc := base64.RawURLEncoding
+	p, m, ok := strings.Cut(s, ".")
+	if !ok {
+		return c, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	sum, err := enc.DecodeString(m)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return c, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
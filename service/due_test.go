@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDueAt(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "due_at")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	due := map[int64]time.Time{
+		1: now.Add(-48 * time.Hour),
+		2: now.Add(-24 * time.Hour),
+		3: now.Add(24 * time.Hour),
+		4: now.Add(10 * 24 * time.Hour),
+	}
+	todos := []service.Todo{}
+	for id, at := range due {
+		todo, err := todoService.Get(ctx, id)
+		assert.NoError(t, err)
+		todo.DueAt = sql.NullTime{Time: at.In(time.FixedZone("WIB", 7*60*60)), Valid: true}
+		if id == 4 {
+			todo.StartAt = sql.NullTime{Time: now.Add(-24 * time.Hour), Valid: true}
+		}
+		todos = append(todos, todo)
+	}
+	affected, err := todoService.Update(ctx, todos)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, affected)
+
+	todo, err := todoService.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, todo.DueAt.Valid)
+	assert.True(t, due[1].Equal(todo.DueAt.Time), "got %v", todo.DueAt.Time)
+	assert.False(t, todo.StartAt.Valid)
+
+	tests := []struct {
+		name   string
+		filter func(service.TodoFilter)
+		want   []any
+	}{
+		{"IsNull", func(f service.TodoFilter) { f.DueAt().IsNull() }, []any{5, 6, 7, 8, 9}},
+		{"Before", func(f service.TodoFilter) { f.DueAt().Before(now) }, []any{1, 2}},
+		{"Before exclusive", func(f service.TodoFilter) { f.DueAt().Before(due[1]) }, []any{}},
+		{"After", func(f service.TodoFilter) { f.DueAt().After(now) }, []any{3, 4}},
+		{"Between inclusive", func(f service.TodoFilter) { f.DueAt().Between(due[1], due[3]) }, []any{1, 2, 3}},
+		{"Within", func(f service.TodoFilter) { f.DueAt().Within(7 * 24 * time.Hour) }, []any{3}},
+		{"Within past", func(f service.TodoFilter) { f.DueAt().Within(-7 * 24 * time.Hour) }, []any{1, 2}},
+		{"StartAt", func(f service.TodoFilter) { f.StartAt().IsNotNull() }, []any{4}},
+		{"Overdue", func(f service.TodoFilter) { f.Overdue() }, []any{1}},
+		{"Not Overdue", func(f service.TodoFilter) { f.Not(f.Overdue()) }, []any{2, 3, 4, 5, 6, 7, 8, 9}},
+		{"Not Before includes NULL", func(f service.TodoFilter) { f.Not(f.DueAt().Before(now)) }, []any{3, 4, 5, 6, 7, 8, 9}},
+		{"Not Within includes NULL", func(f service.TodoFilter) { f.Not(f.DueAt().Within(7 * 24 * time.Hour)) }, []any{1, 2, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := todoService.Filter()
+			tt.filter(filter)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+
+	t.Run("query", func(t *testing.T) {
+		for query, want := range map[string][]any{
+			"overdue:true":           {1},
+			"overdue:false":          {2, 3, 4, 5, 6, 7, 8, 9},
+			"NOT due_at<2000-01-01":  {1, 2, 3, 4, 5, 6, 7, 8, 9},
+			"due_at~7d":              {3},
+			"due_at:null done:false": {5, 7, 9},
+		} {
+			q, err := service.ParseFilterQuery(query)
+			assert.NoError(t, err)
+			filter := todoService.Filter()
+			q.Apply(filter)
+			assert.Equal(t, want, findIDs(t, ctx, todoService, filter), query)
+		}
+	})
+
+	t.Run("spec", func(t *testing.T) {
+		spec, err := service.ParseFilterSpec([]byte(`{"field": "due_at", "op": "within", "value": "-7d"}`))
+		assert.NoError(t, err)
+		filter := todoService.Filter()
+		_, err = spec.Apply(filter)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 2}, findIDs(t, ctx, todoService, filter))
+
+		_, err = service.ParseFilterSpec([]byte(`{"field": "due_at", "op": "lt", "value": "tomorrow"}`))
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats, err := todoService.Stats(ctx, nil, service.StatsByDueDate)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.TodoStats{
+			{Key: "", Total: 5, Done: 2, Open: 3},
+			{Key: due[1].Format(time.DateOnly), Total: 1, Done: 0, Open: 1},
+			{Key: due[2].Format(time.DateOnly), Total: 1, Done: 1, Open: 0},
+			{Key: due[3].Format(time.DateOnly), Total: 1, Done: 0, Open: 1},
+			{Key: due[4].Format(time.DateOnly), Total: 1, Done: 1, Open: 0},
+		}, stats)
+	})
+}
@@ -0,0 +1,8 @@
+package service
+
+// UnregisterMigration removes a Go migration registered by a test.
+func UnregisterMigration(version int64) {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+	delete(goMigrations, version)
+}
@@ -1,15 +1,24 @@
 package service
 
-type Filter interface {
-	Generate(query QueryBuilder)
-}
+import "time"
+
+// Filter is a filter created by a FilterBuilder, to be passed back to the
+// same builder's And, Or or Not.
+type Filter interface{}
 
+// FilterString filters a string. Like and NotLike take a LIKE pattern with %
+// and _ as wildcards; Contains and NotContains match their value anywhere in
+// the string, taking every character as is.
 type FilterString interface {
 	Equal(string) Filter
 	NotEqual(string) Filter
 	Like(string) Filter
 	NotLike(string) Filter
+	Contains(string) Filter
+	NotContains(string) Filter
 	In([]string) Filter
+	IsNull() Filter
+	IsNotNull() Filter
 }
 
 type FilterInt interface {
@@ -22,6 +31,26 @@ type FilterInt interface {
 	Between(int64, int64) Filter
 }
 
+// FilterTime filters a nullable time. Before and After are exclusive,
+// Between is inclusive. Within matches times from now up to now+d, or from
+// now+d up to now when d is negative, with now taken when the query runs.
+type FilterTime interface {
+	Before(time.Time) Filter
+	After(time.Time) Filter
+	Between(time.Time, time.Time) Filter
+	Within(d time.Duration) Filter
+	IsNull() Filter
+	IsNotNull() Filter
+}
+
+// FilterTags filters on the set of tags of a todo. HasAll of no tags
+// matches every todo, as does HasNone; HasAny of no tags matches none.
+type FilterTags interface {
+	HasAny([]string) Filter
+	HasAll([]string) Filter
+	HasNone([]string) Filter
+}
+
 type FilterBool interface {
 	Equal(bool) Filter
 }
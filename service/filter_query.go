@@ -0,0 +1,670 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterSyntaxError reports where ParseFilterQuery gave up. Pos is the byte
+// offset in the query.
+type FilterSyntaxError struct {
+	Msg string
+	Pos int
+}
+
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("filter syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *FilterSyntaxError) Unwrap() error {
+	return ErrInvalidFilter
+}
+
+// FilterQuery is a parsed filter query such as
+//
+//	done:false category:"work" title~report id>=100 (title~a OR description~a)
+//
+// Terms are field, operator and value; bare words and "quoted phrases" search
+// title and description. Terms next to each other are ANDed, OR binds looser
+// than AND, NOT negates the term or group after it.
+//
+// String fields (title, description, category) take : (equal), !: (not
+// equal), ~ (contains) and !~ (does not contain), with null for IsNull and
+// IsNotNull. Int fields (id, category_id, priority) take :, !:, <, <=, > and >=, and
+// lo..hi for an inclusive range. Time fields (due_at, start_at, created_at,
+// updated_at, completed_at) take < and > with a date or RFC 3339 time,
+// :lo..hi for an inclusive range, up to the end of the day when hi is a
+// date, :null and !:null, and ~ with a duration
+// from now such as 7d or -12h. done, overdue and top_level take : and !:
+// with true or false. tags takes a comma separated list of names with : (has
+// all), ~ (has any) and !: (has none).
+type FilterQuery struct {
+	root filterNode
+}
+
+// ParseFilterQuery parses query. Syntax errors are *FilterSyntaxError.
+func ParseFilterQuery(query string) (*FilterQuery, error) {
+	p := &filterParser{src: query}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return &FilterQuery{}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &FilterSyntaxError{Msg: fmt.Sprintf("unexpected %s", tok), Pos: tok.pos}
+	}
+	return &FilterQuery{root: root}, nil
+}
+
+// String returns the canonical form of q, which parses back to the same
+// query.
+func (q *FilterQuery) String() string {
+	if q.root == nil {
+		return ""
+	}
+	return q.root.String()
+}
+
+// Apply adds q to f and returns the filter for its root, nil when q is
+// empty.
+func (q *FilterQuery) Apply(f FilterBuilder) Filter {
+	if q.root == nil {
+		return nil
+	}
+	return q.root.apply(f)
+}
+
+type filterNode interface {
+	String() string
+	apply(FilterBuilder) Filter
+}
+
+type filterAnd []filterNode
+
+func (n filterAnd) String() string {
+	parts := make([]string, len(n))
+	for i, c := range n {
+		if _, ok := c.(filterOr); ok {
+			parts[i] = "(" + c.String() + ")"
+		} else {
+			parts[i] = c.String()
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (n filterAnd) apply(f FilterBuilder) Filter {
+	filters := make([]Filter, len(n))
+	for i, c := range n {
+		filters[i] = c.apply(f)
+	}
+	return f.And(filters...)
+}
+
+type filterOr []filterNode
+
+func (n filterOr) String() string {
+	parts := make([]string, len(n))
+	for i, c := range n {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " OR ")
+}
+
+func (n filterOr) apply(f FilterBuilder) Filter {
+	filters := make([]Filter, len(n))
+	for i, c := range n {
+		filters[i] = c.apply(f)
+	}
+	return f.Or(filters...)
+}
+
+type filterNot struct {
+	node filterNode
+}
+
+func (n filterNot) String() string {
+	switch n.node.(type) {
+	case filterAnd, filterOr:
+		return "NOT (" + n.node.String() + ")"
+	}
+	return "NOT " + n.node.String()
+}
+
+func (n filterNot) apply(f FilterBuilder) Filter {
+	return f.Not(n.node.apply(f))
+}
+
+type filterSearch struct {
+	text string
+}
+
+func (n filterSearch) String() string {
+	return quoteFilterValue(n.text)
+}
+
+func (n filterSearch) apply(f FilterBuilder) Filter {
+	if strings.Contains(n.text, " ") {
+		return f.Search(`"` + n.text + `"`)
+	}
+	return f.Search(n.text)
+}
+
+type filterFieldKind int
+
+const (
+	filterKindString filterFieldKind = iota
+	filterKindInt
+	filterKindBool
+	filterKindTime
+	filterKindTags
+)
+
+var filterFieldKinds = map[string]filterFieldKind{
+	"title":        filterKindString,
+	"description":  filterKindString,
+	"category":     filterKindString,
+	"id":           filterKindInt,
+	"category_id":  filterKindInt,
+	"priority":     filterKindInt,
+	"done":         filterKindBool,
+	"overdue":      filterKindBool,
+	"top_level":    filterKindBool,
+	"due_at":       filterKindTime,
+	"start_at":     filterKindTime,
+	"created_at":   filterKindTime,
+	"updated_at":   filterKindTime,
+	"completed_at": filterKindTime,
+	"tags":         filterKindTags,
+}
+
+var filterOps = map[filterFieldKind][]string{
+	filterKindString: {":", "!:", "~", "!~"},
+	filterKindInt:    {":", "!:", "<", "<=", ">", ">="},
+	filterKindBool:   {":", "!:"},
+	filterKindTime:   {":", "!:", "<", ">", "~"},
+	filterKindTags:   {":", "~", "!:"},
+}
+
+// parseFilterTime reads a date, taken as midnight UTC, or an RFC 3339 time.
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// isFilterDate reports whether s is a date rather than a time.
+func isFilterDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+	return err == nil
+}
+
+// filterBetween matches times from lo to hi inclusive or, when hi is a date,
+// up to the end of that day.
+func filterBetween(f FilterBuilder, ft FilterTime, lo, hi time.Time, hiDate bool) Filter {
+	if hiDate {
+		return f.And(f.Not(ft.Before(lo)), ft.Before(hi.AddDate(0, 0, 1)))
+	}
+	return ft.Between(lo, hi)
+}
+
+// parseFilterDuration reads a time.Duration, also accepting whole days such
+// as 7d or -1d.
+func parseFilterDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// filterInt returns the FilterInt of an int field.
+func filterInt(f FilterBuilder, field string) FilterInt {
+	switch field {
+	case "category_id":
+		return f.CategoryID()
+	case "priority":
+		return f.Priority()
+	}
+	return f.ID()
+}
+
+// filterBool returns the filter matching a bool field equal to v.
+func filterBool(f FilterBuilder, field string, v bool) Filter {
+	var cond func() Filter
+	switch field {
+	case "overdue":
+		cond = f.Overdue
+	case "top_level":
+		cond = f.TopLevel
+	default:
+		return f.Done().Equal(v)
+	}
+	if v {
+		return cond()
+	}
+	return f.Not(cond())
+}
+
+// filterTime returns the FilterTime of a time field.
+func filterTime(f FilterBuilder, field string) FilterTime {
+	switch field {
+	case "start_at":
+		return f.StartAt()
+	case "created_at":
+		return f.CreatedAt()
+	case "updated_at":
+		return f.UpdatedAt()
+	case "completed_at":
+		return f.CompletedAt()
+	}
+	return f.DueAt()
+}
+
+// filterTerm is a field comparison; value is already checked against the
+// field kind.
+type filterTerm struct {
+	field string
+	op    string
+	value string
+	null  bool
+}
+
+func (n filterTerm) String() string {
+	if n.null {
+		return n.field + n.op + "null"
+	}
+	switch filterFieldKinds[n.field] {
+	case filterKindString, filterKindTime, filterKindTags:
+		return n.field + n.op + quoteFilterValue(n.value)
+	}
+	return n.field + n.op + n.value
+}
+
+func (n filterTerm) apply(f FilterBuilder) Filter {
+	switch filterFieldKinds[n.field] {
+	case filterKindString:
+		var fs FilterString
+		switch n.field {
+		case "title":
+			fs = f.Title()
+		case "description":
+			fs = f.Description()
+		case "category":
+			fs = f.Category()
+		}
+		switch {
+		case n.null && n.op == ":":
+			return fs.IsNull()
+		case n.null:
+			return fs.IsNotNull()
+		case n.op == ":":
+			return fs.Equal(n.value)
+		case n.op == "!:":
+			return fs.NotEqual(n.value)
+		case n.op == "~":
+			return fs.Contains(n.value)
+		default:
+			return fs.NotContains(n.value)
+		}
+	case filterKindInt:
+		fi := filterInt(f, n.field)
+		if lo, hi, ok := strings.Cut(n.value, ".."); ok {
+			v1, _ := strconv.ParseInt(lo, 10, 64)
+			v2, _ := strconv.ParseInt(hi, 10, 64)
+			return fi.Between(v1, v2)
+		}
+		v, _ := strconv.ParseInt(n.value, 10, 64)
+		switch n.op {
+		case ":":
+			return fi.Equal(v)
+		case "!:":
+			return fi.NotEqual(v)
+		case "<":
+			return fi.Less(v)
+		case "<=":
+			return fi.LessOrEqual(v)
+		case ">":
+			return fi.Greater(v)
+		default:
+			return fi.GreaterOrEqual(v)
+		}
+	case filterKindTime:
+		ft := filterTime(f, n.field)
+		switch {
+		case n.null && n.op == ":":
+			return ft.IsNull()
+		case n.null:
+			return ft.IsNotNull()
+		case n.op == "~":
+			d, _ := parseFilterDuration(n.value)
+			return ft.Within(d)
+		case n.op == ":":
+			lo, hi, _ := strings.Cut(n.value, "..")
+			t1, _ := parseFilterTime(lo)
+			t2, _ := parseFilterTime(hi)
+			return filterBetween(f, ft, t1, t2, isFilterDate(hi))
+		}
+		t, _ := parseFilterTime(n.value)
+		if n.op == "<" {
+			return ft.Before(t)
+		}
+		return ft.After(t)
+	case filterKindTags:
+		names := strings.Split(n.value, ",")
+		switch n.op {
+		case ":":
+			return f.Tags().HasAll(names)
+		case "~":
+			return f.Tags().HasAny(names)
+		}
+		return f.Tags().HasNone(names)
+	default:
+		v := n.value == "true"
+		if n.op == "!:" {
+			v = !v
+		}
+		return filterBool(f, n.field, v)
+	}
+}
+
+// quoteFilterValue quotes s unless it reads back as the same bare word.
+func quoteFilterValue(s string) string {
+	if s != "" && s != "null" && !isFilterKeyword(s) && !strings.ContainsAny(s, " \t\r\n()\":~<>!\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func isFilterKeyword(s string) bool {
+	return s == "AND" || s == "OR" || s == "NOT"
+}
+
+type filterTokenKind int
+
+const (
+	tokEOF filterTokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type filterToken struct {
+	text string
+	kind filterTokenKind
+	pos  int
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type filterParser struct {
+	src    string
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) lex() error {
+	src := p.src
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, filterToken{text: "(", kind: tokLParen, pos: i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, filterToken{text: ")", kind: tokRParen, pos: i})
+			i++
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return &FilterSyntaxError{Msg: "unterminated string", Pos: i}
+			}
+			p.tokens = append(p.tokens, filterToken{text: b.String(), kind: tokString, pos: i})
+			i = j + 1
+		case strings.IndexByte(":~<>!", c) >= 0:
+			op := ""
+			for _, o := range []string{"!:", "!~", "<=", ">=", ":", "~", "<", ">"} {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return &FilterSyntaxError{Msg: fmt.Sprintf("unknown operator %q", c), Pos: i}
+			}
+			p.tokens = append(p.tokens, filterToken{text: op, kind: tokOp, pos: i})
+			i += len(op)
+		default:
+			j := i
+			for j < len(src) && !strings.ContainsRune(" \t\r\n()\":~<>!", rune(src[j])) {
+				j++
+			}
+			p.tokens = append(p.tokens, filterToken{text: src[i:j], kind: tokWord, pos: i})
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return filterToken{kind: tokEOF, pos: len(p.src)}
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) isKeyword(tok filterToken, kw string) bool {
+	return tok.kind == tokWord && tok.text == kw
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := filterOr{node}
+	for p.isKeyword(p.peek(), "OR") {
+		p.next()
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var nodes filterAnd
+	for {
+		tok := p.peek()
+		if tok.kind == tokEOF || tok.kind == tokRParen || p.isKeyword(tok, "OR") {
+			break
+		}
+		if p.isKeyword(tok, "AND") {
+			p.next()
+			if len(nodes) == 0 {
+				return nil, &FilterSyntaxError{Msg: "AND without left operand", Pos: tok.pos}
+			}
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	switch len(nodes) {
+	case 0:
+		tok := p.peek()
+		return nil, &FilterSyntaxError{Msg: fmt.Sprintf("expected term, got %s", tok), Pos: tok.pos}
+	case 1:
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	tok := p.peek()
+	if p.isKeyword(tok, "NOT") {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, &FilterSyntaxError{Msg: fmt.Sprintf("expected \")\", got %s", end), Pos: end.pos}
+		}
+		return node, nil
+	case tokString:
+		return filterSearch{text: tok.text}, nil
+	case tokWord:
+		if isFilterKeyword(tok.text) {
+			return nil, &FilterSyntaxError{Msg: fmt.Sprintf("unexpected %s", tok), Pos: tok.pos}
+		}
+		if p.peek().kind != tokOp {
+			return filterSearch{text: tok.text}, nil
+		}
+		return p.parseTerm(tok)
+	}
+	return nil, &FilterSyntaxError{Msg: fmt.Sprintf("expected term, got %s", tok), Pos: tok.pos}
+}
+
+func (p *filterParser) parseTerm(field filterToken) (filterNode, error) {
+	name := strings.ToLower(field.text)
+	kind, ok := filterFieldKinds[name]
+	if !ok {
+		return nil, &FilterSyntaxError{Msg: fmt.Sprintf("unknown field %q", field.text), Pos: field.pos}
+	}
+	op := p.next()
+	valid := false
+	for _, o := range filterOps[kind] {
+		valid = valid || o == op.text
+	}
+	if !valid {
+		return nil, &FilterSyntaxError{Msg: fmt.Sprintf("operator %s not valid for %s", op.text, name), Pos: op.pos}
+	}
+	val := p.next()
+	if val.kind != tokWord && val.kind != tokString {
+		return nil, &FilterSyntaxError{Msg: fmt.Sprintf("expected value, got %s", val), Pos: val.pos}
+	}
+	term := filterTerm{field: name, op: op.text, value: val.text}
+	switch kind {
+	case filterKindString:
+		if val.kind == tokWord && val.text == "null" {
+			if op.text != ":" && op.text != "!:" {
+				return nil, &FilterSyntaxError{Msg: fmt.Sprintf("operator %s not valid with null", op.text), Pos: op.pos}
+			}
+			term.null = true
+			term.value = ""
+		}
+	case filterKindInt:
+		lo, hi, isRange := strings.Cut(val.text, "..")
+		if isRange && op.text != ":" {
+			return nil, &FilterSyntaxError{Msg: "range needs operator :", Pos: op.pos}
+		}
+		for _, s := range []string{lo, hi} {
+			if !isRange && s == hi {
+				continue
+			}
+			if _, err := strconv.ParseInt(s, 10, 64); err != nil || val.kind != tokWord {
+				return nil, &FilterSyntaxError{Msg: fmt.Sprintf("invalid number %s for %s", val, name), Pos: val.pos}
+			}
+		}
+	case filterKindBool:
+		if val.kind != tokWord || (val.text != "true" && val.text != "false") {
+			return nil, &FilterSyntaxError{Msg: fmt.Sprintf("invalid bool %s for %s", val, name), Pos: val.pos}
+		}
+	case filterKindTags:
+		for _, name := range strings.Split(val.text, ",") {
+			if name == "" {
+				return nil, &FilterSyntaxError{Msg: fmt.Sprintf("empty tag in %s", val), Pos: val.pos}
+			}
+		}
+	case filterKindTime:
+		switch {
+		case val.kind == tokWord && val.text == "null":
+			if op.text != ":" && op.text != "!:" {
+				return nil, &FilterSyntaxError{Msg: fmt.Sprintf("operator %s not valid with null", op.text), Pos: op.pos}
+			}
+			term.null = true
+			term.value = ""
+		case op.text == "~":
+			if _, err := parseFilterDuration(val.text); err != nil {
+				return nil, &FilterSyntaxError{Msg: fmt.Sprintf("invalid duration %s for %s", val, name), Pos: val.pos}
+			}
+		case op.text == "!:":
+			return nil, &FilterSyntaxError{Msg: fmt.Sprintf("operator !: needs null for %s", name), Pos: op.pos}
+		default:
+			lo, hi, isRange := strings.Cut(val.text, "..")
+			if op.text == ":" && !isRange {
+				return nil, &FilterSyntaxError{Msg: fmt.Sprintf("operator : needs null or a range for %s", name), Pos: val.pos}
+			}
+			if isRange && op.text != ":" {
+				return nil, &FilterSyntaxError{Msg: "range needs operator :", Pos: op.pos}
+			}
+			for _, s := range []string{lo, hi} {
+				if !isRange && s == hi {
+					continue
+				}
+				if _, err := parseFilterTime(s); err != nil {
+					return nil, &FilterSyntaxError{Msg: fmt.Sprintf("invalid time %s for %s", val, name), Pos: val.pos}
+				}
+			}
+		}
+	}
+	return term, nil
+}
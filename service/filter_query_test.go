@@ -0,0 +1,186 @@
+package service_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilterQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{`done:false category:"work" title~report id>=100 (title~a OR description~a)`,
+			`done:false category:work title~report id>=100 (title~a OR description~a)`},
+		{"title:a AND title:b", "title:a title:b"},
+		{"a OR b c", "a OR b c"},
+		{"(a OR b) c", "(a OR b) c"},
+		{"((a))", "a"},
+		{"NOT done:true", "NOT done:true"},
+		{"NOT (a b)", "NOT (a b)"},
+		{`description:null category!:NULL`, `description:null category!:NULL`},
+		{`title:"null" title:"OR"`, `title:"null" title:"OR"`},
+		{`"hello world" title:"say \"hi\""`, `"hello world" title:"say \"hi\""`},
+		{"category_id:1..2 ID!:3", "category_id:1..2 id!:3"},
+		{`due_at>2026-01-01 due_at<"2026-01-01T10:00:00Z"`, `due_at>2026-01-01 due_at<"2026-01-01T10:00:00Z"`},
+		{"due_at:2026-01-01..2026-02-01 start_at!:null overdue:false due_at~-7d",
+			"due_at:2026-01-01..2026-02-01 start_at!:null overdue:false due_at~-7d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := service.ParseFilterQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, q.String())
+
+			again, err := service.ParseFilterQuery(q.String())
+			assert.NoError(t, err)
+			assert.Equal(t, q, again)
+		})
+	}
+}
+
+func TestParseFilterQueryError(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{"owner:me", 0},
+		{"title>a", 5},
+		{"done:yes", 5},
+		{"id:ten", 3},
+		{"id>1..2", 2},
+		{`title~null`, 5},
+		{`title:"open`, 6},
+		{"(a OR b", 7},
+		{"a OR", 4},
+		{"a)", 1},
+		{"AND a", 0},
+		{"title:", 6},
+		{"done:true!", 9},
+		{"due_at:2026-01-01", 7},
+		{"due_at<soon", 7},
+		{"due_at~week", 7},
+		{"due_at!:2026-01-01", 6},
+		{"due_at>2026-01-01..2026-02-01", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := service.ParseFilterQuery(tt.query)
+			var syntaxErr *service.FilterSyntaxError
+			if assert.True(t, errors.As(err, &syntaxErr), "got %v", err) {
+				assert.Equal(t, tt.pos, syntaxErr.Pos, syntaxErr.Error())
+			}
+			assert.ErrorIs(t, err, service.ErrInvalidFilter)
+		})
+	}
+}
+
+func TestFilterQueryApply(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_query")
+
+	tests := []struct {
+		query string
+		want  []any
+	}{
+		{"", []any{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"done:true", []any{2, 4, 6, 8}},
+		{"done!:true", []any{1, 3, 5, 7, 9}},
+		{`category:"work" id>=5`, []any{5, 8}},
+		{"category_id:1..2 id<4", []any{1, 2}},
+		{"description:null category:errand", []any{}},
+		{"description!:null", []any{3, 6, 9}},
+		{"title~1 OR description~9", []any{1, 9}},
+		{"NOT (done:true OR category:home)", []any{3, 5, 9}},
+		{"NOT description~6", []any{1, 2, 3, 4, 5, 7, 8, 9}},
+		{"NOT description:\"desc 3\"", []any{1, 2, 4, 5, 6, 7, 8, 9}},
+		{"id>6 desc", []any{9}},
+		{`title!~"todo 1"`, []any{2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := service.ParseFilterQuery(tt.query)
+			assert.NoError(t, err)
+			filter := todoService.Filter()
+			q.Apply(filter)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+}
+
+func TestFilterQueryValues(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_query_values")
+
+	_, err := todoService.Create(ctx, []service.Todo{
+		{Title: "50% off", Category: service.TodoCategory{ID: 1}},
+		{Title: "500 left", Category: service.TodoCategory{ID: 1}},
+		{Title: `a_b\c`, Category: service.TodoCategory{ID: 1}},
+		{Title: "axb", Category: service.TodoCategory{ID: 1}},
+	})
+	assert.NoError(t, err)
+	for id, due := range map[int64]string{
+		1: "2026-03-10T15:00:00Z", 2: "2026-03-11T00:00:00Z", 3: "2026-03-09T23:59:00Z",
+	} {
+		todo, err := todoService.Get(ctx, id)
+		assert.NoError(t, err)
+		at, err := time.Parse(time.RFC3339, due)
+		assert.NoError(t, err)
+		todo.DueAt = sql.NullTime{Time: at, Valid: true}
+		_, err = todoService.Update(ctx, []service.Todo{todo})
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		query string
+		want  []any
+	}{
+		{"title~50%", []any{10}},
+		{"title~a_b", []any{12}},
+		{`title~"\\c"`, []any{12}},
+		{"title!~% id>9", []any{11, 12, 13}},
+		{"due_at:2026-03-10..2026-03-10", []any{1}},
+		{"due_at:2026-03-09..2026-03-10", []any{1, 3}},
+		{`due_at:"2026-03-09..2026-03-10T00:00:00Z"`, []any{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := service.ParseFilterQuery(tt.query)
+			assert.NoError(t, err)
+			filter := todoService.Filter()
+			q.Apply(filter)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+
+	spec, err := service.ParseFilterSpec([]byte(`{"field": "due_at", "op": "between", "value": ["2026-03-10", "2026-03-10"]}`))
+	assert.NoError(t, err)
+	filter := todoService.Filter()
+	_, err = spec.Apply(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1}, findIDs(t, ctx, todoService, filter))
+
+	spec, err = service.ParseFilterSpec([]byte(`{"field": "title", "op": "contains", "value": "0%"}`))
+	assert.NoError(t, err)
+	filter = todoService.Filter()
+	_, err = spec.Apply(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{10}, findIDs(t, ctx, todoService, filter))
+
+	t.Run("Like pattern", func(t *testing.T) {
+		filter := todoService.Filter()
+		filter.Title().Like(`a_b\c`)
+		assert.Equal(t, []any{12}, findIDs(t, ctx, todoService, filter), "backslash taken as is")
+		filter = todoService.Filter()
+		filter.Title().Like("a_b%")
+		assert.Equal(t, []any{12, 13}, findIDs(t, ctx, todoService, filter))
+		filter = todoService.Filter()
+		filter.Title().NotContains("a_b")
+		filter.ID().Greater(9)
+		assert.Equal(t, []any{10, 11, 13}, findIDs(t, ctx, todoService, filter))
+	})
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// FilterSpec is a filter as data, for clients to send and for storing. A
+// spec is exactly one of: a field condition (Field, Op and Value), a Search,
+// or an And, Or or Not group.
+//
+//	{"and": [
+//	  {"field": "done", "op": "eq", "value": false},
+//	  {"or": [
+//	    {"field": "title", "op": "like", "value": "%report%"},
+//	    {"field": "description", "op": "is_null"}
+//	  ]}
+//	]}
+//
+// Fields and their ops are
+//
+//	title, description, category   eq ne like not_like contains not_contains
+//	                               in is_null is_not_null
+//	id, category_id, priority      eq ne lt le gt ge between
+//	due_at, start_at, created_at,  lt gt between within is_null is_not_null
+//	updated_at, completed_at
+//	done, overdue, top_level       eq
+//	tags                           has_any has_all has_none
+//
+// in and the tags ops take a list of strings, between a list of two numbers
+// or times.
+// Times are RFC 3339 strings or dates, a date as the upper bound of between
+// taking in its whole day; within takes a duration from now such as "168h" or
+// "-7d". An empty spec or group adds no condition.
+type FilterSpec struct {
+	Value  any          `json:"value,omitempty"`
+	Not    *FilterSpec  `json:"not,omitempty"`
+	Field  string       `json:"field,omitempty"`
+	Op     string       `json:"op,omitempty"`
+	Search string       `json:"search,omitempty"`
+	And    []FilterSpec `json:"and,omitempty"`
+	Or     []FilterSpec `json:"or,omitempty"`
+}
+
+var filterSpecOps = map[filterFieldKind][]string{
+	filterKindString: {"eq", "ne", "like", "not_like", "contains", "not_contains", "in", "is_null", "is_not_null"},
+	filterKindInt:    {"eq", "ne", "lt", "le", "gt", "ge", "between"},
+	filterKindBool:   {"eq"},
+	filterKindTime:   {"lt", "gt", "between", "within", "is_null", "is_not_null"},
+	filterKindTags:   {"has_any", "has_all", "has_none"},
+}
+
+// ParseFilterSpec decodes and validates a JSON FilterSpec.
+func ParseFilterSpec(data []byte) (FilterSpec, error) {
+	var spec FilterSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return spec, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return spec, spec.Validate()
+}
+
+// Validate checks s without applying it. Errors wrap ErrInvalidFilter and
+// name the offending spec, e.g. "and[1].value".
+func (s FilterSpec) Validate() error {
+	_, err := s.apply(nil, "")
+	return err
+}
+
+// Apply adds s to f and returns the filter for its root, nil when s is
+// empty. Nothing is added to f when s is invalid.
+func (s FilterSpec) Apply(f FilterBuilder) (Filter, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s.apply(f, "")
+}
+
+// apply validates s and, when f is not nil, builds it on f.
+func (s FilterSpec) apply(f FilterBuilder, path string) (Filter, error) {
+	kinds := 0
+	for _, set := range []bool{s.Field != "" || s.Op != "" || s.Value != nil, s.Search != "", s.And != nil, s.Or != nil, s.Not != nil} {
+		if set {
+			kinds++
+		}
+	}
+	switch {
+	case kinds == 0:
+		return nil, nil
+	case kinds > 1:
+		return nil, specError(path, "more than one of field, search, and, or, not")
+	case s.Search != "":
+		if f == nil {
+			return nil, nil
+		}
+		return f.Search(s.Search), nil
+	case s.And != nil:
+		return s.applyGroup(f, path+"and", s.And, FilterBuilder.And)
+	case s.Or != nil:
+		return s.applyGroup(f, path+"or", s.Or, FilterBuilder.Or)
+	case s.Not != nil:
+		filter, err := s.Not.apply(f, path+"not.")
+		if err != nil || f == nil {
+			return nil, err
+		}
+		return f.Not(filter), nil
+	}
+	return s.applyField(f, path)
+}
+
+func (s FilterSpec) applyGroup(
+	f FilterBuilder, path string, specs []FilterSpec, group func(FilterBuilder, ...Filter) Filter,
+) (Filter, error) {
+	filters := make([]Filter, len(specs))
+	for i, spec := range specs {
+		filter, err := spec.apply(f, fmt.Sprintf("%s[%d].", path, i))
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = filter
+	}
+	if f == nil {
+		return nil, nil
+	}
+	return group(f, filters...), nil
+}
+
+func (s FilterSpec) applyField(f FilterBuilder, path string) (Filter, error) {
+	kind, ok := filterFieldKinds[s.Field]
+	if !ok {
+		return nil, specError(path+"field", fmt.Sprintf("unknown field %q", s.Field))
+	}
+	valid := false
+	for _, op := range filterSpecOps[kind] {
+		valid = valid || op == s.Op
+	}
+	if !valid {
+		return nil, specError(path+"op", fmt.Sprintf("op %q not valid for %s", s.Op, s.Field))
+	}
+	path += "value"
+	switch kind {
+	case filterKindString:
+		return s.applyString(f, path)
+	case filterKindInt:
+		return s.applyInt(f, path)
+	case filterKindTime:
+		return s.applyTime(f, path)
+	case filterKindTags:
+		return s.applyTags(f, path)
+	}
+	v, ok := s.Value.(bool)
+	if !ok {
+		return nil, specError(path, fmt.Sprintf("expected bool, got %T", s.Value))
+	}
+	if f == nil {
+		return nil, nil
+	}
+	return filterBool(f, s.Field, v), nil
+}
+
+func (s FilterSpec) applyString(f FilterBuilder, path string) (Filter, error) {
+	var str string
+	var list []string
+	switch s.Op {
+	case "is_null", "is_not_null":
+		if s.Value != nil {
+			return nil, specError(path, "unexpected value")
+		}
+	case "in":
+		var err error
+		if list, err = specStrings(s.Value, path); err != nil {
+			return nil, err
+		}
+	default:
+		var ok bool
+		if str, ok = s.Value.(string); !ok {
+			return nil, specError(path, fmt.Sprintf("expected string, got %T", s.Value))
+		}
+	}
+	if f == nil {
+		return nil, nil
+	}
+	var fs FilterString
+	switch s.Field {
+	case "title":
+		fs = f.Title()
+	case "description":
+		fs = f.Description()
+	default:
+		fs = f.Category()
+	}
+	switch s.Op {
+	case "eq":
+		return fs.Equal(str), nil
+	case "ne":
+		return fs.NotEqual(str), nil
+	case "like":
+		return fs.Like(str), nil
+	case "not_like":
+		return fs.NotLike(str), nil
+	case "contains":
+		return fs.Contains(str), nil
+	case "not_contains":
+		return fs.NotContains(str), nil
+	case "in":
+		return fs.In(list), nil
+	case "is_null":
+		return fs.IsNull(), nil
+	}
+	return fs.IsNotNull(), nil
+}
+
+func (s FilterSpec) applyTags(f FilterBuilder, path string) (Filter, error) {
+	names, err := specStrings(s.Value, path)
+	if err != nil || f == nil {
+		return nil, err
+	}
+	switch s.Op {
+	case "has_any":
+		return f.Tags().HasAny(names), nil
+	case "has_all":
+		return f.Tags().HasAll(names), nil
+	}
+	return f.Tags().HasNone(names), nil
+}
+
+// specStrings accepts a list of strings.
+func specStrings(v any, path string) ([]string, error) {
+	values, ok := v.([]any)
+	if !ok {
+		return nil, specError(path, fmt.Sprintf("expected list, got %T", v))
+	}
+	list := make([]string, len(values))
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			return nil, specError(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("expected string, got %T", v))
+		}
+		list[i] = str
+	}
+	return list, nil
+}
+
+func (s FilterSpec) applyInt(f FilterBuilder, path string) (Filter, error) {
+	var values []int64
+	if s.Op == "between" {
+		list, ok := s.Value.([]any)
+		if !ok || len(list) != 2 {
+			return nil, specError(path, "expected list of two numbers")
+		}
+		for i, v := range list {
+			n, err := specInt(v)
+			if err != nil {
+				return nil, specError(fmt.Sprintf("%s[%d]", path, i), err.Error())
+			}
+			values = append(values, n)
+		}
+	} else {
+		n, err := specInt(s.Value)
+		if err != nil {
+			return nil, specError(path, err.Error())
+		}
+		values = append(values, n)
+	}
+	if f == nil {
+		return nil, nil
+	}
+	fi := filterInt(f, s.Field)
+	switch s.Op {
+	case "eq":
+		return fi.Equal(values[0]), nil
+	case "ne":
+		return fi.NotEqual(values[0]), nil
+	case "lt":
+		return fi.Less(values[0]), nil
+	case "le":
+		return fi.LessOrEqual(values[0]), nil
+	case "gt":
+		return fi.Greater(values[0]), nil
+	case "ge":
+		return fi.GreaterOrEqual(values[0]), nil
+	}
+	return fi.Between(values[0], values[1]), nil
+}
+
+func (s FilterSpec) applyTime(f FilterBuilder, path string) (Filter, error) {
+	var values []time.Time
+	var within time.Duration
+	switch s.Op {
+	case "is_null", "is_not_null":
+		if s.Value != nil {
+			return nil, specError(path, "unexpected value")
+		}
+	case "within":
+		str, ok := s.Value.(string)
+		if !ok {
+			return nil, specError(path, fmt.Sprintf("expected duration, got %T", s.Value))
+		}
+		d, err := parseFilterDuration(str)
+		if err != nil {
+			return nil, specError(path, err.Error())
+		}
+		within = d
+	case "between":
+		list, ok := s.Value.([]any)
+		if !ok || len(list) != 2 {
+			return nil, specError(path, "expected list of two times")
+		}
+		for i, v := range list {
+			t, err := specTime(v)
+			if err != nil {
+				return nil, specError(fmt.Sprintf("%s[%d]", path, i), err.Error())
+			}
+			values = append(values, t)
+		}
+	default:
+		t, err := specTime(s.Value)
+		if err != nil {
+			return nil, specError(path, err.Error())
+		}
+		values = append(values, t)
+	}
+	if f == nil {
+		return nil, nil
+	}
+	ft := filterTime(f, s.Field)
+	switch s.Op {
+	case "is_null":
+		return ft.IsNull(), nil
+	case "is_not_null":
+		return ft.IsNotNull(), nil
+	case "within":
+		return ft.Within(within), nil
+	case "lt":
+		return ft.Before(values[0]), nil
+	case "gt":
+		return ft.After(values[0]), nil
+	}
+	hi, _ := s.Value.([]any)[1].(string)
+	return filterBetween(f, ft, values[0], values[1], isFilterDate(hi)), nil
+}
+
+// specTime accepts a time.Time or a string read by parseFilterTime.
+func specTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return parseFilterTime(t)
+	}
+	return time.Time{}, fmt.Errorf("expected time, got %T", v)
+}
+
+// specInt accepts the integer numbers a decoded spec may hold.
+func specInt(v any) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case json.Number:
+		return n.Int64()
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), nil
+		}
+		return 0, fmt.Errorf("%v is not an integer", n)
+	}
+	return 0, fmt.Errorf("expected number, got %T", v)
+}
+
+func specError(path, msg string) error {
+	if path = strings.TrimSuffix(path, "."); path == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidFilter, msg)
+	}
+	return fmt.Errorf("%w: %s: %s", ErrInvalidFilter, path, msg)
+}
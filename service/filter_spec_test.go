@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSpec(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_spec")
+
+	tests := []struct {
+		name string
+		spec string
+		want []any
+	}{
+		{"empty", `{}`, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"empty group", `{"or": [{}, {"not": {}}]}`, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"bool", `{"field": "done", "op": "eq", "value": true}`, []any{2, 4, 6, 8}},
+		{"int", `{"field": "id", "op": "ge", "value": 7}`, []any{7, 8, 9}},
+		{"between", `{"field": "category_id", "op": "between", "value": [2, 3]}`, []any{2, 3, 5, 6, 8, 9}},
+		{"in", `{"field": "category", "op": "in", "value": ["home", "errand"]}`, []any{1, 3, 4, 6, 7, 9}},
+		{"is_null", `{"field": "description", "op": "is_not_null"}`, []any{3, 6, 9}},
+		{"search", `{"search": "desc"}`, []any{3, 6, 9}},
+		{"and", `{"and": [
+			{"field": "done", "op": "eq", "value": false},
+			{"field": "title", "op": "like", "value": "%o 3"}
+		]}`, []any{3}},
+		{"or not", `{"or": [
+			{"field": "id", "op": "eq", "value": 1},
+			{"not": {"field": "category", "op": "ne", "value": "errand"}}
+		]}`, []any{1, 3, 6, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := service.ParseFilterSpec([]byte(tt.spec))
+			assert.NoError(t, err)
+			filter := todoService.Filter()
+			_, err = spec.Apply(filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+
+			data, err := json.Marshal(spec)
+			assert.NoError(t, err)
+			again, err := service.ParseFilterSpec(data)
+			assert.NoError(t, err)
+			assert.Equal(t, spec, again)
+		})
+	}
+}
+
+func TestFilterSpecInvalid(t *testing.T) {
+	tests := []struct {
+		spec string
+		msg  string
+	}{
+		{`[]`, "invalid filter: json: cannot unmarshal array into Go value of type service.FilterSpec"},
+		{`{"field": "owner", "op": "eq", "value": "me"}`, `invalid filter: field: unknown field "owner"`},
+		{`{"field": "title", "op": "gt", "value": "a"}`, `invalid filter: op: op "gt" not valid for title`},
+		{`{"field": "title", "op": "eq", "value": 1}`, "invalid filter: value: expected string, got float64"},
+		{`{"field": "title", "op": "is_null", "value": "a"}`, "invalid filter: value: unexpected value"},
+		{`{"field": "id", "op": "eq", "value": 1.5}`, "invalid filter: value: 1.5 is not an integer"},
+		{`{"field": "id", "op": "between", "value": [1]}`, "invalid filter: value: expected list of two numbers"},
+		{`{"field": "done", "op": "eq", "value": "yes"}`, "invalid filter: value: expected bool, got string"},
+		{`{"not": {"and": [{"field": "category", "op": "in", "value": ["a", 2]}]}}`,
+			"invalid filter: not.and[0].value[1]: expected string, got float64"},
+		{`{"search": "a", "field": "title"}`, "invalid filter: more than one of field, search, and, or, not"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := service.ParseFilterSpec([]byte(tt.spec))
+			assert.ErrorIs(t, err, service.ErrInvalidFilter)
+			assert.EqualError(t, err, tt.msg)
+		})
+	}
+}
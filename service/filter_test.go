@@ -0,0 +1,179 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	service_impl "github.com/senomas/gotodo_service_sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupFilterTodos opens a fresh in-memory database named name with three
+// categories and nine todos: todo i belongs to category ((i-1)%3)+1, is done
+// when i is even and has a description when i is a multiple of 3.
+func setupFilterTodos(t *testing.T, name string) (context.Context, service.TodoService) {
+	db, err := sql.Open(service_impl.DriverName, fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	assert.NoError(t, err, "failed to open db")
+	t.Cleanup(func() { db.Close() })
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.NoError(t, todoService.Migrate(ctx))
+
+	_, err = todoService.CreateCategory(ctx, []service.TodoCategory{
+		{Name: "home"}, {Name: "work"}, {Name: "errand"},
+	})
+	assert.NoError(t, err)
+	todos := []service.Todo{}
+	for i := 1; i <= 9; i++ {
+		todo := service.Todo{
+			Title:    fmt.Sprintf("todo %d", i),
+			Category: service.TodoCategory{ID: int64((i-1)%3 + 1)},
+			Done:     i%2 == 0,
+		}
+		if i%3 == 0 {
+			todo.Description = sql.NullString{String: fmt.Sprintf("desc %d", i), Valid: true}
+		}
+		todos = append(todos, todo)
+	}
+	_, err = todoService.Create(ctx, todos)
+	assert.NoError(t, err)
+	return ctx, todoService
+}
+
+func findIDs(t *testing.T, ctx context.Context, todoService service.TodoService, filter service.TodoFilter) []any {
+	total, todos, err := todoService.Find(ctx, filter, nil, 0, 100)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(todos), total)
+	return Apply(todos, func(v any) any { return int(v.(service.Todo).ID) })
+}
+
+func TestFilterInt(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_int")
+
+	tests := []struct {
+		name   string
+		filter func(service.FilterInt)
+		want   []any
+	}{
+		{"Equal", func(f service.FilterInt) { f.Equal(2) }, []any{2, 5, 8}},
+		{"NotEqual", func(f service.FilterInt) { f.NotEqual(2) }, []any{1, 3, 4, 6, 7, 9}},
+		{"Less", func(f service.FilterInt) { f.Less(2) }, []any{1, 4, 7}},
+		{"LessOrEqual", func(f service.FilterInt) { f.LessOrEqual(2) }, []any{1, 2, 4, 5, 7, 8}},
+		{"Greater", func(f service.FilterInt) { f.Greater(2) }, []any{3, 6, 9}},
+		{"GreaterOrEqual", func(f service.FilterInt) { f.GreaterOrEqual(2) }, []any{2, 3, 5, 6, 8, 9}},
+		{"Between inclusive", func(f service.FilterInt) { f.Between(2, 3) }, []any{2, 3, 5, 6, 8, 9}},
+		{"Between single", func(f service.FilterInt) { f.Between(1, 1) }, []any{1, 4, 7}},
+		{"Between empty", func(f service.FilterInt) { f.Between(3, 2) }, []any{}},
+		{"combined", func(f service.FilterInt) { f.GreaterOrEqual(2); f.Less(3) }, []any{2, 5, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := todoService.Filter()
+			tt.filter(filter.CategoryID())
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+}
+
+func TestFilterBool(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_bool")
+
+	filter := todoService.Filter()
+	filter.Done().Equal(false)
+	assert.Equal(t, []any{1, 3, 5, 7, 9}, findIDs(t, ctx, todoService, filter))
+
+	filter = todoService.Filter()
+	filter.Done().Equal(true)
+	assert.Equal(t, []any{2, 4, 6, 8}, findIDs(t, ctx, todoService, filter))
+}
+
+func TestFilterString(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_string")
+
+	tests := []struct {
+		name   string
+		filter func(service.TodoFilter)
+		want   []any
+	}{
+		{"title NotEqual", func(f service.TodoFilter) { f.Title().NotEqual("todo 1") }, []any{2, 3, 4, 5, 6, 7, 8, 9}},
+		{"title NotLike", func(f service.TodoFilter) { f.Title().NotLike("%1") }, []any{2, 3, 4, 5, 6, 7, 8, 9}},
+		{"description Equal", func(f service.TodoFilter) { f.Description().Equal("desc 3") }, []any{3}},
+		{"description NotEqual includes NULL", func(f service.TodoFilter) { f.Description().NotEqual("desc 3") }, []any{1, 2, 4, 5, 6, 7, 8, 9}},
+		{"description NotLike includes NULL", func(f service.TodoFilter) { f.Description().NotLike("%6") }, []any{1, 2, 3, 4, 5, 7, 8, 9}},
+		{"description IsNull", func(f service.TodoFilter) { f.Description().IsNull() }, []any{1, 2, 4, 5, 7, 8}},
+		{"description IsNotNull", func(f service.TodoFilter) { f.Description().IsNotNull() }, []any{3, 6, 9}},
+		{"description IsNotNull and NotEqual", func(f service.TodoFilter) {
+			f.Description().IsNotNull()
+			f.Description().NotEqual("desc 3")
+		}, []any{6, 9}},
+		{"category NotEqual", func(f service.TodoFilter) { f.Category().NotEqual("home") }, []any{2, 3, 5, 6, 8, 9}},
+		{"with done", func(f service.TodoFilter) {
+			f.Category().NotEqual("home")
+			f.Done().Equal(true)
+		}, []any{2, 6, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := todoService.Filter()
+			tt.filter(filter)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+}
+
+func TestFilterGroup(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "filter_group")
+
+	tests := []struct {
+		name   string
+		filter func(service.TodoFilter)
+		want   []any
+	}{
+		{"Or", func(f service.TodoFilter) {
+			f.Or(f.Title().Like("%1"), f.Description().Like("%6"))
+		}, []any{1, 6}},
+		{"Or with top level", func(f service.TodoFilter) {
+			f.Done().Equal(true)
+			f.Or(f.Title().Like("%1"), f.Description().Like("%6"), f.CategoryID().Equal(2))
+		}, []any{2, 6, 8}},
+		{"Not", func(f service.TodoFilter) {
+			f.Not(f.CategoryID().Equal(1))
+		}, []any{2, 3, 5, 6, 8, 9}},
+		{"Not Or", func(f service.TodoFilter) {
+			f.Not(f.Or(f.CategoryID().Equal(1), f.Done().Equal(true)))
+		}, []any{3, 5, 9}},
+		{"Or of And", func(f service.TodoFilter) {
+			f.Or(
+				f.And(f.CategoryID().Equal(1), f.Done().Equal(true)),
+				f.And(f.CategoryID().Equal(3), f.Not(f.Done().Equal(true))),
+			)
+		}, []any{3, 4, 9}},
+		{"Not Like includes NULL", func(f service.TodoFilter) {
+			f.Not(f.Description().Like("%6"))
+		}, []any{1, 2, 3, 4, 5, 7, 8, 9}},
+		{"Not Equal includes NULL", func(f service.TodoFilter) {
+			f.Not(f.Description().Equal("desc 3"))
+		}, []any{1, 2, 4, 5, 6, 7, 8, 9}},
+		{"Not Or includes NULL", func(f service.TodoFilter) {
+			f.Not(f.Or(f.Description().Equal("desc 3"), f.CategoryID().Equal(1)))
+		}, []any{2, 5, 6, 8, 9}},
+		{"Not Not", func(f service.TodoFilter) {
+			f.Not(f.Not(f.Description().Like("%6")))
+		}, []any{6}},
+		{"empty Or", func(f service.TodoFilter) {
+			f.Or()
+			f.CategoryID().Equal(2)
+		}, []any{2, 5, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := todoService.Filter()
+			tt.filter(filter)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+}
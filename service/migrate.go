@@ -1,29 +1,34 @@
 package service
 
 import (
-	"bufio"
 	"context"
 	"crypto/sha512"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
-	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type Migration struct {
-	Timestamp time.Time
-	Filename  string
-	Hash      string
-	Result    string
-	ID        int64
-	Success   bool
+	Timestamp  time.Time
+	Filename   string
+	Hash       string
+	Result     string
+	ID         int64
+	Version    int64
+	Success    bool
+	RolledBack bool
 }
 
-func FileHash(filename string) (string, error) {
-	f, err := os.Open(filename)
+// FileHash returns the hex SHA-512 of filename in fsys.
+func FileHash(fsys fs.FS, filename string) (string, error) {
+	f, err := fsys.Open(filename)
 	if err != nil {
 		return "", fmt.Errorf("error reading %s: %v", filename, err)
 	}
@@ -41,62 +46,370 @@ func FileHash(filename string) (string, error) {
 	}
 }
 
+// MigrationHashError is returned by Migrate when a migration file that was
+// already applied no longer matches the hash recorded in _migration.
+type MigrationHashError struct {
+	Filename    string
+	AppliedHash string
+	FileHash    string
+}
+
+func (e *MigrationHashError) Error() string {
+	return fmt.Sprintf("migration %s modified after apply: applied hash %s, file hash %s", e.Filename, e.AppliedHash, e.FileHash)
+}
+
+func (e *MigrationHashError) Unwrap() error {
+	return ErrMigrationModified
+}
+
+// MigrationVersion returns the numeric prefix of a migration filename, e.g.
+// 2 for "002.add_index.up.sql".
+func MigrationVersion(filename string) (int64, error) {
+	v, _, _ := strings.Cut(filename, ".")
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid migration filename %s: %v", filename, err)
+	}
+	return version, nil
+}
+
+// isDownMigration reports whether filename is the down half of a migration
+// pair; Migrate never applies these.
+func isDownMigration(filename string) bool {
+	return strings.HasSuffix(filename, ".down.sql")
+}
+
+// downMigration returns the down file paired with an up migration, or "" for
+// forward-only files.
+func downMigration(filename string) string {
+	if name, ok := strings.CutSuffix(filename, ".up.sql"); ok {
+		return name + ".down.sql"
+	}
+	return ""
+}
+
+// Migrate applies every up file in fsys, together with the registered Go
+// migrations, in version order. getMigrate must return the last successful,
+// not rolled back Migration recorded for a filename, or ErrNoData when the
+// file was never applied. Files already applied with the same hash are
+// skipped.
+//
+// When beginTx is not nil each file runs inside its own transaction, otherwise
+// statements go through migrateQuery one by one. addMigrate records a
+// successful file with the transaction of the file, so the record commits
+// with the schema change or not at all; tx is nil without beginTx. A file
+// that fails is still reported to addMigrate, outside any transaction, with
+// Success false and the error in Result.
 func Migrate(
-	ctx context.Context, path string, addMigrate func(context.Context, Migration) error,
+	ctx context.Context, fsys fs.FS, getMigrate func(context.Context, string) (Migration, error),
+	addMigrate func(ctx context.Context, tx *sql.Tx, m Migration) error,
+	beginTx func(context.Context) (*sql.Tx, error),
 	migrateQuery func(context.Context, string) error,
 ) error {
-	slog.Debug("Migrate", "path", path)
-	files, err := os.ReadDir(path)
+	steps, err := migrationSteps(fsys)
 	if err != nil {
 		return err
 	}
-	for _, f := range files {
-		fp := filepath.Join(path, f.Name())
-		hash, err := FileHash(fp)
-		if err != nil {
+	for _, st := range steps {
+		applied, err := getMigrate(ctx, st.filename)
+		if err == nil {
+			if applied.Hash != st.hash {
+				return &MigrationHashError{Filename: st.filename, AppliedHash: applied.Hash, FileHash: st.hash}
+			}
+			slog.Debug("Migrate skip", "file", st.filename, "id", applied.ID)
+			continue
+		} else if !errors.Is(err, ErrNoData) {
 			return err
 		}
 		m := Migration{
-			Filename: f.Name(),
-			Hash:     hash,
+			Filename: st.filename,
+			Hash:     st.hash,
+			Version:  st.version,
 			Result:   "",
 			Success:  false,
 		}
-
-		fin, err := os.Open(fp)
+		err = migrateTx(ctx, beginTx, migrateQuery, func(tx *sql.Tx, exec func(context.Context, string) error) error {
+			var err error
+			if st.up != nil {
+				err = runGoMigration(ctx, st.filename, st.up, tx, &m)
+			} else {
+				err = migrateFile(ctx, fsys, st.filename, &m, exec)
+			}
+			if err != nil {
+				return err
+			}
+			m.Success = true
+			m.Timestamp = time.Now()
+			if err := addMigrate(ctx, tx, m); err != nil {
+				m.Result = fmt.Sprintf("%sERROR: recording migration: %v\n", m.Result, err)
+				return fmt.Errorf("error recording %s: %w", st.filename, err)
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("error reading %s: %v", fp, err)
+			m.Success = false
+			m.Timestamp = time.Now()
+			if aerr := addMigrate(ctx, nil, m); aerr != nil {
+				return errors.Join(err, fmt.Errorf("error recording failure of %s: %w", st.filename, aerr))
+			}
+			return err
 		}
-		defer fin.Close()
-		slog.Debug("Migrate", "file", fp)
-		scanner := bufio.NewScanner(fin)
-		qry := ""
-		for scanner.Scan() {
-			ln := scanner.Text()
-			qry = fmt.Sprintf("%s%s\n", qry, ln)
-			if strings.HasSuffix(strings.TrimSpace(ln), ";") {
-				err := migrateQuery(ctx, qry)
-				if err != nil {
-					m.Result = fmt.Sprintf("%s%s\nERROR: %v\n", m.Result, qry, err)
-					return fmt.Errorf("error migrating %s: [%s]\n%v", fp, qry, err)
-				} else {
-					m.Result = fmt.Sprintf("%s%s\n", m.Result, qry)
-				}
-				qry = ""
+	}
+	return nil
+}
+
+// Rollback runs the down file of every applied migration in fsys with a
+// version greater than targetVersion, newest first, and reports each one to
+// rollbackMigrate so it can be marked as rolled back, with the transaction of
+// the down file as addMigrate of Migrate gets it. When one of them has no down
+// migration it fails with ErrNoDownMigration before rolling back any.
+func Rollback(
+	ctx context.Context, fsys fs.FS, targetVersion int64,
+	getMigrate func(context.Context, string) (Migration, error),
+	rollbackMigrate func(ctx context.Context, tx *sql.Tx, m Migration) error,
+	beginTx func(context.Context) (*sql.Tx, error),
+	migrateQuery func(context.Context, string) error,
+) error {
+	slog.Debug("Rollback", "version", targetVersion)
+	steps, err := migrationSteps(fsys)
+	if err != nil {
+		return err
+	}
+	// every down migration is checked before any runs, so a rollback that
+	// reaches a forward-only migration changes nothing
+	var rollback []migrationStep
+	var migrations []Migration
+	for i := len(steps) - 1; i >= 0; i-- {
+		st := steps[i]
+		if st.version <= targetVersion {
+			continue
+		}
+		m, err := getMigrate(ctx, st.filename)
+		if errors.Is(err, ErrNoData) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if st.goCode {
+			if st.down == nil {
+				return fmt.Errorf("%w: %s", ErrNoDownMigration, st.filename)
 			}
+		} else if st.downFile == "" {
+			return fmt.Errorf("%w: %s", ErrNoDownMigration, st.filename)
+		} else if _, err := fs.Stat(fsys, st.downFile); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrNoDownMigration, st.filename, err)
 		}
-		if strings.TrimSpace(qry) != "" {
-			err := migrateQuery(ctx, qry)
+		rollback = append(rollback, st)
+		migrations = append(migrations, m)
+	}
+	for i, st := range rollback {
+		applied := migrations[i]
+		applied.Result = ""
+		err = migrateTx(ctx, beginTx, migrateQuery, func(tx *sql.Tx, exec func(context.Context, string) error) error {
+			var err error
+			if st.goCode {
+				err = runGoMigration(ctx, st.filename, st.down, tx, &applied)
+			} else {
+				err = migrateFile(ctx, fsys, st.downFile, &applied, exec)
+			}
 			if err != nil {
-				return fmt.Errorf("error migrating %s: [%s]\n%v", fp, qry, err)
+				return err
 			}
-		}
-		m.Success = true
-		m.Timestamp = time.Now()
-		err = addMigrate(ctx, m)
+			applied.RolledBack = true
+			applied.Timestamp = time.Now()
+			return rollbackMigrate(ctx, tx, applied)
+		})
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// migrationStep is one up migration: a SQL file from fsys or a registered Go
+// migration.
+type migrationStep struct {
+	up       GoMigration
+	down     GoMigration
+	filename string
+	downFile string
+	hash     string
+	version  int64
+	goCode   bool
+}
+
+// migrationSteps merges the up files in fsys with the registered Go
+// migrations, ordered by version and then name.
+func migrationSteps(fsys fs.FS) ([]migrationStep, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var steps []migrationStep
+	for _, f := range files {
+		if f.IsDir() || isDownMigration(f.Name()) {
+			continue
+		}
+		st := migrationStep{filename: f.Name(), downFile: downMigration(f.Name())}
+		st.version, err = MigrationVersion(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		st.hash, err = FileHash(fsys, f.Name())
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+	steps = append(steps, goMigrationSteps()...)
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].version != steps[j].version {
+			return steps[i].version < steps[j].version
+		}
+		return steps[i].filename < steps[j].filename
+	})
+	return steps, nil
+}
+
+// migrateTx calls fn with a new transaction from beginTx and an executor
+// bound to it, committing when fn succeeds. Without beginTx fn gets a nil
+// transaction and migrateQuery as is.
+func migrateTx(
+	ctx context.Context, beginTx func(context.Context) (*sql.Tx, error),
+	migrateQuery func(context.Context, string) error,
+	fn func(*sql.Tx, func(context.Context, string) error) error,
+) error {
+	if beginTx == nil {
+		return fn(nil, migrateQuery)
+	}
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(tx, func(ctx context.Context, qry string) error {
+		_, err := tx.ExecContext(ctx, qry)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateFile executes the statements of fp in order, appending each one to
+// m.Result.
+func migrateFile(
+	ctx context.Context, fsys fs.FS, fp string, m *Migration,
+	migrateQuery func(context.Context, string) error,
+) error {
+	script, err := fs.ReadFile(fsys, fp)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", fp, err)
+	}
+	slog.Debug("Migrate", "file", fp)
+	for _, qry := range SplitStatements(string(script)) {
+		err := migrateQuery(ctx, qry)
+		if err != nil {
+			m.Result = fmt.Sprintf("%s%s\nERROR: %v\n", m.Result, qry, err)
+			return fmt.Errorf("error migrating %s: [%s]\n%v", fp, qry, err)
+		}
+		m.Result = fmt.Sprintf("%s%s\n", m.Result, qry)
+	}
+	return nil
+}
+
+type MigrationState int
+
+const (
+	MigrationPending MigrationState = iota
+	MigrationApplied
+	MigrationModified
+)
+
+func (s MigrationState) String() string {
+	switch s {
+	case MigrationPending:
+		return "pending"
+	case MigrationApplied:
+		return "applied"
+	case MigrationModified:
+		return "modified"
+	}
+	return fmt.Sprintf("MigrationState(%d)", int(s))
+}
+
+// MigrationEntry is the status of one up migration file. Applied holds the
+// recorded Migration for applied and modified files.
+type MigrationEntry struct {
+	Filename string
+	Hash     string
+	Applied  Migration
+	Version  int64
+	State    MigrationState
+}
+
+// MigrationStatus compares the up files in fsys and the registered Go
+// migrations with the migrations recorded through getMigrate, without
+// executing anything.
+func MigrationStatus(
+	ctx context.Context, fsys fs.FS, getMigrate func(context.Context, string) (Migration, error),
+) ([]MigrationEntry, error) {
+	steps, err := migrationSteps(fsys)
+	if err != nil {
+		return nil, err
+	}
+	var entries []MigrationEntry
+	for _, st := range steps {
+		e := MigrationEntry{Filename: st.filename, Hash: st.hash, Version: st.version}
+		e.Applied, err = getMigrate(ctx, st.filename)
+		if err == nil {
+			if e.Applied.Hash == e.Hash {
+				e.State = MigrationApplied
+			} else {
+				e.State = MigrationModified
+			}
+		} else if errors.Is(err, ErrNoData) {
+			e.State = MigrationPending
+		} else {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// MigrateDryRun writes the statements Migrate would execute to w, without
+// executing or recording them. Go migrations are listed by name only.
+func MigrateDryRun(
+	ctx context.Context, fsys fs.FS, getMigrate func(context.Context, string) (Migration, error),
+	w io.Writer,
+) error {
+	entries, err := MigrationStatus(ctx, fsys, getMigrate)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		switch e.State {
+		case MigrationModified:
+			return &MigrationHashError{Filename: e.Filename, AppliedHash: e.Applied.Hash, FileHash: e.Hash}
+		case MigrationPending:
+			if _, err := fmt.Fprintf(w, "-- %s\n", e.Filename); err != nil {
+				return err
+			}
+			if isGoMigration(e.Filename) {
+				continue
+			}
+			script, err := fs.ReadFile(fsys, e.Filename)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %v", e.Filename, err)
+			}
+			for _, qry := range SplitStatements(string(script)) {
+				if _, err := fmt.Fprintf(w, "%s\n", qry); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"crypto/sha512"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// GoMigration is a migration step written in Go. It runs inside the same
+// transaction that Migrate records the step for.
+type GoMigration func(ctx context.Context, tx *sql.Tx) error
+
+type goMigration struct {
+	up      GoMigration
+	down    GoMigration
+	name    string
+	version int64
+}
+
+var (
+	goMigrationsMu sync.Mutex
+	goMigrations   = map[int64]goMigration{}
+)
+
+// RegisterMigration registers a Go migration that Migrate runs between the
+// SQL files of lower and higher versions. down may be nil for forward-only
+// migrations. It panics when name is empty, up is nil or version is already
+// registered, and is meant to be called from init functions.
+func RegisterMigration(version int64, name string, up, down GoMigration) {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+	if up == nil {
+		panic("service: RegisterMigration up is nil")
+	}
+	if name == "" || strings.Contains(name, "/") {
+		panic(fmt.Sprintf("service: RegisterMigration invalid name %q", name))
+	}
+	if m, dup := goMigrations[version]; dup {
+		panic(fmt.Sprintf("service: RegisterMigration called twice for version %d (%s, %s)", version, m.name, name))
+	}
+	goMigrations[version] = goMigration{up: up, down: down, name: name, version: version}
+}
+
+// goMigrationFilename is the name a Go migration is recorded under in
+// _migration.
+func goMigrationFilename(version int64, name string) string {
+	return fmt.Sprintf("%03d.%s.go", version, name)
+}
+
+func isGoMigration(filename string) bool {
+	return strings.HasSuffix(filename, ".go")
+}
+
+func goMigrationSteps() []migrationStep {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+	steps := make([]migrationStep, 0, len(goMigrations))
+	for _, m := range goMigrations {
+		filename := goMigrationFilename(m.version, m.name)
+		steps = append(steps, migrationStep{
+			up:       m.up,
+			down:     m.down,
+			filename: filename,
+			hash:     fmt.Sprintf("%x", sha512.Sum512([]byte(filename))),
+			version:  m.version,
+			goCode:   true,
+		})
+	}
+	return steps
+}
+
+// runGoMigration runs fn in tx, which Go migrations always require.
+func runGoMigration(ctx context.Context, filename string, fn GoMigration, tx *sql.Tx, m *Migration) error {
+	if tx == nil {
+		m.Result = fmt.Sprintf("%sERROR: %s requires a transaction\n", m.Result, filename)
+		return fmt.Errorf("error migrating %s: go migration requires a transaction", filename)
+	}
+	err := fn(ctx, tx)
+	if err != nil {
+		m.Result = fmt.Sprintf("%sgo %s\nERROR: %v\n", m.Result, filename, err)
+		return fmt.Errorf("error migrating %s: %v", filename, err)
+	}
+	m.Result = fmt.Sprintf("%sgo %s\n", m.Result, filename)
+	return nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// MigrationLockPoll is how often WithMigrationLock retries a held lock.
+var MigrationLockPoll = 100 * time.Millisecond
+
+// WithMigrationLock runs fn while holding the migration lock of a database.
+// tryLock must atomically claim the lock, returning false when another
+// process holds it: a lock row claimed under BEGIN IMMEDIATE on SQLite, an
+// advisory lock on dialects that have one. WithMigrationLock keeps retrying
+// for timeout and then fails with ErrMigrationLocked. unlock is called once
+// fn returns, even when ctx is already cancelled.
+func WithMigrationLock(
+	ctx context.Context, timeout time.Duration,
+	tryLock func(context.Context) (bool, error), unlock func(context.Context) error,
+	fn func(context.Context) error,
+) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w: not released after %v", ErrMigrationLocked, timeout)
+		}
+		slog.Debug("WithMigrationLock wait", "poll", MigrationLockPoll)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(MigrationLockPoll):
+		}
+	}
+	defer func() {
+		if err := unlock(context.WithoutCancel(ctx)); err != nil {
+			slog.Warn("WithMigrationLock unlock failed", "error", err)
+		}
+	}()
+	return fn(ctx)
+}
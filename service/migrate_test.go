@@ -0,0 +1,473 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+	service_impl "github.com/senomas/gotodo_service_sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+type memMigrations struct {
+	migrations []service.Migration
+	queries    []string
+	fail       string
+}
+
+func (m *memMigrations) get(ctx context.Context, filename string) (service.Migration, error) {
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if m.migrations[i].Filename == filename && m.migrations[i].Success && !m.migrations[i].RolledBack {
+			return m.migrations[i], nil
+		}
+	}
+	return service.Migration{}, service.ErrNoData
+}
+
+func (m *memMigrations) add(ctx context.Context, tx *sql.Tx, mg service.Migration) error {
+	mg.ID = int64(len(m.migrations) + 1)
+	m.migrations = append(m.migrations, mg)
+	return nil
+}
+
+func (m *memMigrations) rollback(ctx context.Context, tx *sql.Tx, mg service.Migration) error {
+	m.migrations[mg.ID-1].RolledBack = true
+	return nil
+}
+
+func (m *memMigrations) query(ctx context.Context, qry string) error {
+	if m.fail != "" && strings.Contains(qry, m.fail) {
+		return errors.New("query failed")
+	}
+	m.queries = append(m.queries, qry)
+	return nil
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001.a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\n")},
+	}
+
+	m := &memMigrations{}
+	t.Run("apply", func(t *testing.T) {
+		err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+		assert.NoError(t, err)
+		assert.Len(t, m.migrations, 1)
+		assert.Len(t, m.queries, 1)
+	})
+
+	t.Run("skip applied", func(t *testing.T) {
+		fsys["002.b.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id INTEGER);\n")}
+		err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+		assert.NoError(t, err)
+		assert.Len(t, m.migrations, 2)
+		assert.Equal(t, []string{"CREATE TABLE a (id INTEGER);", "CREATE TABLE b (id INTEGER);"}, m.queries)
+	})
+
+	t.Run("detect modified", func(t *testing.T) {
+		fsys["001.a.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE a (id TEXT);\n")}
+		err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+		assert.ErrorIs(t, err, service.ErrMigrationModified)
+		var herr *service.MigrationHashError
+		if assert.True(t, errors.As(err, &herr)) {
+			assert.Equal(t, "001.a.sql", herr.Filename)
+			assert.Equal(t, m.migrations[0].Hash, herr.AppliedHash)
+		}
+		assert.Len(t, m.migrations, 2)
+	})
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001.a.sql":      {Data: []byte("CREATE TABLE a (id INTEGER);\n")},
+		"002.b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);\n")},
+		"002.b.down.sql": {Data: []byte("DROP TABLE b;\n")},
+		"003.c.up.sql":   {Data: []byte("CREATE TABLE c (id INTEGER);\n")},
+		"003.c.down.sql": {Data: []byte("DROP TABLE c;\n")},
+	}
+
+	m := &memMigrations{}
+	err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"CREATE TABLE a (id INTEGER);",
+		"CREATE TABLE b (id INTEGER);",
+		"CREATE TABLE c (id INTEGER);",
+	}, m.queries)
+
+	t.Run("rollback to 1", func(t *testing.T) {
+		m.queries = nil
+		err := service.Rollback(ctx, fsys, 1, m.get, m.rollback, nil, m.query)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"DROP TABLE c;", "DROP TABLE b;"}, m.queries)
+		assert.False(t, m.migrations[0].RolledBack)
+		assert.True(t, m.migrations[1].RolledBack)
+		assert.True(t, m.migrations[2].RolledBack)
+	})
+
+	t.Run("migrate again", func(t *testing.T) {
+		m.queries = nil
+		err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"CREATE TABLE b (id INTEGER);", "CREATE TABLE c (id INTEGER);"}, m.queries)
+	})
+
+	t.Run("rollback forward-only", func(t *testing.T) {
+		m.queries = nil
+		err := service.Rollback(ctx, fsys, 0, m.get, m.rollback, nil, m.query)
+		assert.ErrorIs(t, err, service.ErrNoDownMigration)
+		assert.Empty(t, m.queries, "nothing rolled back")
+		for _, mg := range m.migrations[3:] {
+			assert.False(t, mg.RolledBack, mg.Filename)
+		}
+	})
+}
+
+func TestRollbackForwardOnly(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:rollback_forward_only?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.NoError(t, todoService.Migrate(ctx))
+
+	err = todoService.Rollback(ctx, 0)
+	assert.ErrorIs(t, err, service.ErrNoDownMigration)
+	assert.ErrorContains(t, err, "001.todo.sql")
+	entries, err := todoService.MigrationStatus(ctx)
+	assert.NoError(t, err)
+	for _, e := range entries {
+		assert.Equal(t, service.MigrationApplied, e.State, e.Filename)
+	}
+	var count int64
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'todo_tag'").Scan(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count, "schema untouched")
+}
+
+func TestMigrateFailure(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001.a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\nCREATE TABLE b (id INTEGER);\n")},
+	}
+
+	m := &memMigrations{fail: "TABLE b"}
+	err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+	assert.Error(t, err)
+	if assert.Len(t, m.migrations, 1) {
+		assert.False(t, m.migrations[0].Success)
+		assert.Equal(t, "001.a.sql", m.migrations[0].Filename)
+		assert.Contains(t, m.migrations[0].Result, "CREATE TABLE a (id INTEGER);")
+		assert.Contains(t, m.migrations[0].Result, "ERROR: query failed")
+	}
+
+	m.fail = ""
+	err = service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+	assert.NoError(t, err)
+	if assert.Len(t, m.migrations, 2) {
+		assert.True(t, m.migrations[1].Success)
+	}
+}
+
+func TestMigrateTx(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_tx?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := context.WithValue(context.Background(), service.ServiceContextDB, db)
+	todoService := service_impl.TodoService{MigrationFS: fstest.MapFS{
+		"001.a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\nCREATE TABLE a (id INTEGER);\n")},
+	}}
+	assert.Error(t, todoService.Migrate(ctx))
+
+	var count int64
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'").Scan(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	var success bool
+	var result string
+	err = db.QueryRow("SELECT success, result FROM _migration WHERE filename = '001.a.sql'").Scan(&success, &result)
+	assert.NoError(t, err)
+	assert.False(t, success)
+	assert.Contains(t, result, "ERROR: table a already exists")
+}
+
+func TestMigrateRecordInTx(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_record?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001.a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\n")},
+	}
+	var failures []service.Migration
+	add := func(ctx context.Context, tx *sql.Tx, m service.Migration) error {
+		if m.Success {
+			assert.NotNil(t, tx)
+			return errors.New("disk full")
+		}
+		assert.Nil(t, tx)
+		failures = append(failures, m)
+		return nil
+	}
+	beginTx := func(ctx context.Context) (*sql.Tx, error) { return db.BeginTx(ctx, nil) }
+	get := func(context.Context, string) (service.Migration, error) {
+		return service.Migration{}, service.ErrNoData
+	}
+	err = service.Migrate(ctx, fsys, get, add, beginTx, nil)
+	assert.ErrorContains(t, err, "disk full")
+
+	var count int64
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'").Scan(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count, "rolled back with its record")
+	if assert.Len(t, failures, 1) {
+		assert.False(t, failures[0].Success)
+		assert.Contains(t, failures[0].Result, "ERROR: recording migration: disk full")
+	}
+
+	err = service.Migrate(ctx, fsys, get, func(context.Context, *sql.Tx, service.Migration) error {
+		return errors.New("still full")
+	}, beginTx, nil)
+	assert.ErrorContains(t, err, "error recording 001.a.sql: still full")
+	assert.ErrorContains(t, err, "error recording failure of 001.a.sql: still full")
+}
+
+func TestMigrationStatus(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001.a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\n")},
+		"002.b.sql": {Data: []byte("CREATE TABLE b (id INTEGER);\n")},
+	}
+	m := &memMigrations{}
+	err := service.Migrate(ctx, fsys, m.get, m.add, nil, m.query)
+	assert.NoError(t, err)
+
+	fsys["002.b.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id TEXT);\n")}
+	fsys["003.c.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id INTEGER);\n")}
+	fsys["003.c.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE c;\n")}
+	entries, err := service.MigrationStatus(ctx, fsys, m.get)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]any{"001.a.sql applied", "002.b.sql modified", "003.c.up.sql pending"},
+		Apply(entries, func(v any) any {
+			e := v.(service.MigrationEntry)
+			return e.Filename + " " + e.State.String()
+		}))
+
+	t.Run("dry-run", func(t *testing.T) {
+		fsys["002.b.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id INTEGER);\n")}
+		var out strings.Builder
+		err := service.MigrateDryRun(ctx, fsys, m.get, &out)
+		assert.NoError(t, err)
+		assert.Equal(t, "-- 003.c.up.sql\nCREATE TABLE c (id INTEGER);\n", out.String())
+		assert.Len(t, m.migrations, 2)
+	})
+}
+
+func TestGoMigration(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_go?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	service.RegisterMigration(2, "backfill", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO a (name) VALUES ('go')")
+		return err
+	}, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM a WHERE name = 'go'")
+		return err
+	})
+	defer service.UnregisterMigration(2)
+
+	ctx := context.WithValue(context.Background(), service.ServiceContextDB, db)
+	todoService := service_impl.TodoService{MigrationFS: fstest.MapFS{
+		"001.a.sql":      {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT);\n")},
+		"003.b.up.sql":   {Data: []byte("INSERT INTO a (name) SELECT name || ' sql' FROM a;\n")},
+		"003.b.down.sql": {Data: []byte("DELETE FROM a WHERE name LIKE '% sql';\n")},
+	}}
+	assert.NoError(t, todoService.Migrate(ctx))
+
+	names := func() []string {
+		var names []string
+		rows, err := db.Query("SELECT name FROM a ORDER BY id")
+		assert.NoError(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			assert.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"go", "go sql"}, names())
+
+	entries, err := todoService.MigrationStatus(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]any{"001.a.sql applied", "002.backfill.go applied", "003.b.up.sql applied"},
+		Apply(entries, func(v any) any {
+			e := v.(service.MigrationEntry)
+			return e.Filename + " " + e.State.String()
+		}))
+
+	assert.NoError(t, todoService.Rollback(ctx, 1))
+	assert.Empty(t, names())
+}
+
+func TestMigrationLock(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_lock?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := context.WithValue(context.Background(), service.ServiceContextDB, db)
+	todoService := service_impl.TodoService{MigrationLockTimeout: 300 * time.Millisecond}
+	assert.NoError(t, todoService.Migrate(ctx))
+
+	t.Run("released", func(t *testing.T) {
+		var count int64
+		err := db.QueryRow("SELECT COUNT(*) FROM _migration_lock").Scan(&count)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, count)
+	})
+
+	t.Run("held by other", func(t *testing.T) {
+		_, err := db.Exec("INSERT INTO _migration_lock (id, owner, locked_at) VALUES (1, 'other', ?)", time.Now())
+		assert.NoError(t, err)
+		err = todoService.Migrate(ctx)
+		assert.ErrorIs(t, err, service.ErrMigrationLocked)
+		err = todoService.Rollback(ctx, 0)
+		assert.ErrorIs(t, err, service.ErrMigrationLocked)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		_, err := db.Exec("UPDATE _migration_lock SET locked_at = ?", time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, todoService.Migrate(ctx))
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		errs := make(chan error, 4)
+		for i := 0; i < cap(errs); i++ {
+			go func() {
+				errs <- service_impl.TodoService{}.Migrate(ctx)
+			}()
+		}
+		for i := 0; i < cap(errs); i++ {
+			assert.NoError(t, <-errs)
+		}
+		entries, err := todoService.MigrationStatus(ctx)
+		assert.NoError(t, err)
+		var count int64
+		err = db.QueryRow("SELECT COUNT(*) FROM _migration").Scan(&count)
+		assert.NoError(t, err)
+		assert.EqualValues(t, len(entries), count)
+	})
+}
+
+func TestMigrationLockRefresh(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_lock_refresh?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	var lockedAt time.Time
+	service.RegisterMigration(2, "check_lock", func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT locked_at FROM _migration_lock").Scan(&lockedAt)
+	}, nil)
+	defer service.UnregisterMigration(2)
+
+	ctx := context.WithValue(context.Background(), service.ServiceContextDB, db)
+	todoService := service_impl.TodoService{MigrationFS: fstest.MapFS{
+		"001.a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\nUPDATE _migration_lock SET locked_at = '2000-01-01 00:00:00+00:00';\n")},
+		// another process taking the lock over while b runs
+		"003.b.sql": {Data: []byte("CREATE TABLE b (id INTEGER);\nUPDATE _migration_lock SET owner = 'other';\n")},
+	}}
+	err = todoService.Migrate(ctx)
+	assert.ErrorIs(t, err, service.ErrMigrationLocked)
+	assert.WithinDuration(t, time.Now(), lockedAt, time.Minute, "refreshed after 001.a.sql")
+
+	var count int64
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'").Scan(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count, "rolled back")
+	err = db.QueryRow("SELECT COUNT(*) FROM _migration WHERE success").Scan(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestMigrateTwice(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_twice?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.NoError(t, todoService.Migrate(ctx))
+	assert.NoError(t, todoService.Migrate(ctx))
+
+	entries, err := todoService.MigrationStatus(ctx)
+	assert.NoError(t, err)
+	var count int64
+	err = db.QueryRow("SELECT COUNT(*) FROM _migration").Scan(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(entries), count)
+}
+
+func TestMigrationStatusReadOnly(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_status_ro?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	tables := func() int64 {
+		var count int64
+		err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+
+	entries, err := todoService.MigrationStatus(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	for _, e := range entries {
+		assert.Equal(t, service.MigrationPending, e.State, e.Filename)
+	}
+	var out strings.Builder
+	assert.NoError(t, todoService.MigrateDryRun(ctx, &out))
+	assert.Contains(t, out.String(), "-- 001.todo.sql")
+	assert.EqualValues(t, 0, tables(), "nothing created")
+
+	t.Run("before rollback support", func(t *testing.T) {
+		_, err := db.Exec(`CREATE TABLE _migration (
+      id INTEGER PRIMARY KEY AUTOINCREMENT,
+      filename TEXT NOT NULL,
+      hash TEXT NOT NULL,
+      success BOOLEAN NOT NULL,
+      result TEXT,
+      timestamp DATETIME NOT NULL
+    )`)
+		assert.NoError(t, err)
+		_, err = db.Exec("INSERT INTO _migration (filename, hash, success, result, timestamp) VALUES ('001.todo.sql', 'x', TRUE, '', CURRENT_TIMESTAMP)")
+		assert.NoError(t, err)
+
+		entries, err := todoService.MigrationStatus(ctx)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, entries) {
+			assert.Equal(t, service.MigrationModified, entries[0].State)
+		}
+		var count int64
+		err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('_migration') WHERE name = 'rolled_back'").Scan(&count)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, count, "not altered")
+	})
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPage(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "find_page")
+	getID := func(v any) any { return int(v.(service.Todo).ID) }
+
+	tests := []struct {
+		name string
+		sort service.Sort
+	}{
+		{"default", nil},
+		{"category", service.Sort{service.Asc(service.SortCategory)}},
+		{"done desc, title", service.Sort{service.Desc(service.SortDone), service.Asc(service.SortTitle)}},
+		{"id desc", service.Sort{service.Desc(service.SortID)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, todos, err := todoService.Find(ctx, nil, tt.sort, 0, 100)
+			assert.NoError(t, err)
+			var ids []any
+			cursor := ""
+			for i := 0; i < 10; i++ {
+				page, err := todoService.FindPage(ctx, nil, tt.sort, cursor, 2)
+				assert.NoError(t, err)
+				ids = append(ids, Apply(page.Todos, getID)...)
+				if page.Next == "" {
+					break
+				}
+				cursor = page.Next
+			}
+			assert.Equal(t, Apply(todos, getID), ids)
+		})
+	}
+
+	t.Run("with filter", func(t *testing.T) {
+		filter := todoService.Filter()
+		filter.Done().Equal(false)
+		page, err := todoService.FindPage(ctx, filter, nil, "", 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 3, 5}, Apply(page.Todos, getID))
+		page, err = todoService.FindPage(ctx, filter, nil, page.Next, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{7, 9}, Apply(page.Todos, getID))
+		assert.Empty(t, page.Next)
+	})
+
+	t.Run("insert between pages", func(t *testing.T) {
+		sort := service.Sort{service.Asc(service.SortTitle)}
+		page, err := todoService.FindPage(ctx, nil, sort, "", 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 2, 3}, Apply(page.Todos, getID))
+		_, err = todoService.Create(ctx, []service.Todo{
+			{Title: "todo 0", Category: service.TodoCategory{ID: 1}},
+		})
+		assert.NoError(t, err)
+		page, err = todoService.FindPage(ctx, nil, sort, page.Next, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{4, 5, 6}, Apply(page.Todos, getID))
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		page, err := todoService.FindPage(ctx, nil, nil, "", 2)
+		assert.NoError(t, err)
+
+		tampered := []byte(page.Next)
+		tampered[2] ^= 1
+		_, err = todoService.FindPage(ctx, nil, nil, string(tampered), 2)
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+
+		_, err = todoService.FindPage(ctx, nil, service.Sort{service.Desc(service.SortID)}, page.Next, 2)
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+
+		_, err = todoService.FindPage(ctx, nil, nil, "garbage", 2)
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	})
+}
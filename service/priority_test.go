@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "priority")
+
+	now := time.Now()
+	set := map[int64]struct {
+		priority service.Priority
+		due      time.Duration
+	}{
+		1: {service.PriorityLow, -24 * time.Hour},
+		2: {service.PriorityUrgent, -24 * time.Hour},
+		3: {service.PriorityMedium, -48 * time.Hour},
+		4: {service.PriorityHigh, 0},
+		5: {service.PriorityHigh, 24 * time.Hour},
+		6: {service.PriorityHigh, 48 * time.Hour},
+		7: {service.PriorityNone, time.Hour},
+	}
+	todos := []service.Todo{}
+	for id, s := range set {
+		todo, err := todoService.Get(ctx, id)
+		assert.NoError(t, err)
+		todo.Priority = s.priority
+		if s.due != 0 {
+			todo.DueAt = sql.NullTime{Time: now.Add(s.due), Valid: true}
+		}
+		todos = append(todos, todo)
+	}
+	_, err := todoService.Update(ctx, todos)
+	assert.NoError(t, err)
+
+	todo, err := todoService.Get(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, service.PriorityUrgent, todo.Priority)
+	assert.Equal(t, "urgent", todo.Priority.String())
+
+	t.Run("filter", func(t *testing.T) {
+		filter := todoService.Filter()
+		filter.Priority().GreaterOrEqual(int64(service.PriorityHigh))
+		assert.Equal(t, []any{2, 4, 5, 6}, findIDs(t, ctx, todoService, filter))
+
+		q, err := service.ParseFilterQuery("priority:1..2")
+		assert.NoError(t, err)
+		filter = todoService.Filter()
+		q.Apply(filter)
+		assert.Equal(t, []any{1, 3}, findIDs(t, ctx, todoService, filter))
+
+		urgent := service.PriorityUrgent
+		filter = todoService.Filter()
+		filter.Overdue()
+		affected, err := todoService.UpdateWhere(ctx, filter, service.TodoPatch{Priority: &urgent}, false)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 2, affected)
+		filter = todoService.Filter()
+		filter.Priority().Equal(int64(service.PriorityUrgent))
+		assert.Equal(t, []any{1, 2, 3}, findIDs(t, ctx, todoService, filter))
+
+		// put them back for the sort tests
+		for _, id := range []int64{1, 3} {
+			priority := set[id].priority
+			filter = todoService.Filter()
+			filter.ID().Equal(id)
+			_, err = todoService.UpdateWhere(ctx, filter, service.TodoPatch{Priority: &priority}, false)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("sort", func(t *testing.T) {
+		_, todos, err := todoService.Find(ctx, nil, service.Sort{service.Asc(service.SortDueAt)}, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{3, 1, 2, 7, 5, 6, 4, 8, 9}, Apply(todos, func(v any) any { return int(v.(service.Todo).ID) }))
+
+		want := []any{3, 1, 2, 5, 6, 4, 7, 8, 9}
+		_, todos, err = todoService.Find(ctx, nil, service.SortSmart, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, want, Apply(todos, func(v any) any { return int(v.(service.Todo).ID) }))
+
+		var ids []any
+		page := service.Page{}
+		for {
+			page, err = todoService.FindPage(ctx, nil, service.SortSmart, page.Next, 2)
+			assert.NoError(t, err)
+			ids = append(ids, Apply(page.Todos, func(v any) any { return int(v.(service.Todo).ID) })...)
+			if page.Next == "" {
+				break
+			}
+		}
+		assert.Equal(t, want, ids)
+	})
+}
@@ -0,0 +1,42 @@
+//go:build sqlite_fts5 || fts5
+
+package service_test
+
+import (
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchRank(t *testing.T) {
+	ctx, todoService := setupSearchTodos(t, "search_rank")
+
+	filter := todoService.Filter()
+	filter.Search("budget")
+	_, todos, err := todoService.Find(ctx, filter, service.Sort{service.Asc(service.SortRank)}, 0, 10)
+	assert.NoError(t, err)
+	ids := Apply(todos, func(v any) any { return int(v.(service.Todo).ID) })
+	if assert.Len(t, ids, 3) {
+		assert.Equal(t, 5, ids[0])
+		assert.ElementsMatch(t, []any{2, 3}, ids[1:])
+	}
+	for _, todo := range todos {
+		switch todo.ID {
+		case 2:
+			assert.Equal(t, "report on the quarterly [budget]", todo.Snippet)
+		case 3:
+			assert.Equal(t, "[budget] airline, 50% off", todo.Snippet)
+		}
+	}
+
+	t.Run("rank needs search", func(t *testing.T) {
+		_, _, err := todoService.Find(ctx, nil, service.Sort{service.Asc(service.SortRank)}, 0, 10)
+		assert.ErrorIs(t, err, service.ErrInvalidSort)
+	})
+
+	t.Run("not pageable", func(t *testing.T) {
+		_, err := todoService.FindPage(ctx, filter, service.Sort{service.Asc(service.SortRank)}, "", 10)
+		assert.ErrorIs(t, err, service.ErrInvalidSort)
+	})
+}
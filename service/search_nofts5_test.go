@@ -0,0 +1,51 @@
+//go:build !(sqlite_fts5 || fts5)
+
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	service_impl "github.com/senomas/gotodo_service_sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchRank(t *testing.T) {
+	ctx, todoService := setupSearchTodos(t, "search_rank")
+
+	filter := todoService.Filter()
+	filter.Search("budget")
+	_, _, err := todoService.Find(ctx, filter, service.Sort{service.Asc(service.SortRank)}, 0, 10)
+	assert.ErrorIs(t, err, service.ErrUnsupported)
+	assert.EqualError(t, err, "not available in this build: search ranking needs FTS5")
+}
+
+func TestSearchNot(t *testing.T) {
+	ctx, todoService := setupSearchTodos(t, "search_not")
+
+	filter := todoService.Filter()
+	filter.Not(filter.Search("zzz"))
+	assert.Equal(t, []any{1, 2, 3, 4, 5}, findIDs(t, ctx, todoService, filter))
+
+	filter = todoService.Filter()
+	filter.Not(filter.Search("budget"))
+	affected, err := todoService.DeleteWhere(ctx, filter, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, affected)
+	assert.Equal(t, []any{2, 3, 5}, findIDs(t, ctx, todoService, nil))
+}
+
+func TestMigrateFTSDatabase(t *testing.T) {
+	db, err := sql.Open(service_impl.DriverName, "file:migrate_fts?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	t.Cleanup(func() { db.Close() })
+	// stands in for the FTS5 table of a build with the tag
+	_, err = db.Exec("CREATE TABLE todo_fts (title, description)")
+	assert.NoError(t, err)
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.ErrorIs(t, todoService.Migrate(ctx), service.ErrUnsupported)
+}
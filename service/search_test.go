@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	service_impl "github.com/senomas/gotodo_service_sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSearchTodos(t *testing.T, name string) (context.Context, service.TodoService) {
+	db, err := sql.Open(service_impl.DriverName, "file:"+name+"?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	t.Cleanup(func() { db.Close() })
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.NoError(t, todoService.Migrate(ctx))
+	_, err = todoService.CreateCategory(ctx, []service.TodoCategory{{Name: "work"}})
+	assert.NoError(t, err)
+	desc := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	_, err = todoService.Create(ctx, []service.Todo{
+		{Title: "write quarterly report", Category: service.TodoCategory{ID: 1}},
+		{Title: "review", Description: desc("report on the quarterly budget"), Category: service.TodoCategory{ID: 1}},
+		{Title: "book flights", Description: desc("budget airline, 50% off"), Category: service.TodoCategory{ID: 1}},
+		{Title: "reporter interview", Category: service.TodoCategory{ID: 1}},
+		{Title: "quarterly budget review", Description: desc("budget budget budget"), Category: service.TodoCategory{ID: 1}},
+	})
+	assert.NoError(t, err)
+	return ctx, todoService
+}
+
+func TestSearch(t *testing.T) {
+	ctx, todoService := setupSearchTodos(t, "search")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []any
+	}{
+		{"word", "budget", []any{2, 3, 5}},
+		{"prefix", "report", []any{1, 2, 4}},
+		{"every word", "quarterly budget", []any{2, 5}},
+		{"phrase", `"quarterly report"`, []any{1}},
+		{"phrase and word", `"budget review" quarterly`, []any{5}},
+		{"special characters", `50% off`, []any{3}},
+		{"no match", "holiday", []any{}},
+		{"empty", "  ", []any{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := todoService.Filter()
+			filter.Search(tt.query)
+			assert.Equal(t, tt.want, findIDs(t, ctx, todoService, filter))
+		})
+	}
+
+	t.Run("in Or", func(t *testing.T) {
+		filter := todoService.Filter()
+		filter.Or(filter.Search("flights"), filter.Title().Equal("review"))
+		assert.Equal(t, []any{2, 3}, findIDs(t, ctx, todoService, filter))
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		_, err := todoService.Update(ctx, []service.Todo{
+			{ID: 4, Title: "holiday planning", Category: service.TodoCategory{ID: 1}},
+		})
+		assert.NoError(t, err)
+		filter := todoService.Filter()
+		filter.Search("holiday")
+		assert.Equal(t, []any{4}, findIDs(t, ctx, todoService, filter))
+		filter = todoService.Filter()
+		filter.Search("reporter")
+		assert.Equal(t, []any{}, findIDs(t, ctx, todoService, filter))
+	})
+}
@@ -17,4 +17,16 @@ var (
 	ErrNoDBInContext = errors.New("DB not found in context")
 	ErrNoData        = errors.New("no data")
 	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidGroup  = errors.New("invalid group")
+	ErrEmptyFilter   = errors.New("empty filter")
+	ErrEmptyPatch    = errors.New("empty patch")
+	ErrCategoryInUse = errors.New("category in use")
+	ErrParentCycle   = errors.New("parent cycle")
+	ErrUnsupported   = errors.New("not available in this build")
+
+	ErrMigrationModified = errors.New("migration modified")
+	ErrNoDownMigration   = errors.New("no down migration")
+	ErrMigrationLocked   = errors.New("migration locked")
 )
@@ -0,0 +1,48 @@
+package service
+
+// SortField names a Todo attribute Find can order by. Backends map each field
+// onto a column of their own and reject any field they do not know with
+// ErrInvalidSort, so a SortField is never spliced into a query as is.
+type SortField string
+
+const (
+	SortID       SortField = "id"
+	SortTitle    SortField = "title"
+	SortCategory SortField = "category"
+	SortDone     SortField = "done"
+	SortCreated  SortField = "created_at"
+	SortUpdated  SortField = "updated_at"
+	// SortCompleted puts open todos, which have no CompletedAt, first.
+	SortCompleted SortField = "completed_at"
+	SortPriority  SortField = "priority"
+	// SortDueAt puts todos without a due date last.
+	SortDueAt SortField = "due_at"
+	// SortOverdue orders by whether a todo is overdue, as Overdue filters,
+	// with now taken when the query runs.
+	SortOverdue SortField = "overdue"
+	// SortRank orders by search relevance, best match first, and is only
+	// valid with a Search filter. Backends without ranking fail with
+	// ErrUnsupported.
+	SortRank SortField = "rank"
+)
+
+type SortKey struct {
+	Field SortField `json:"field"`
+	Desc  bool      `json:"desc"`
+}
+
+// Sort orders Find results by its keys in turn. Backends break remaining ties
+// by id, ascending, so pages never overlap.
+type Sort []SortKey
+
+// SortSmart is the triage order: overdue todos first, then by priority,
+// highest first, then by due date, soonest first.
+var SortSmart = Sort{Desc(SortOverdue), Desc(SortPriority), Asc(SortDueAt)}
+
+func Asc(field SortField) SortKey {
+	return SortKey{Field: field}
+}
+
+func Desc(field SortField) SortKey {
+	return SortKey{Field: field, Desc: true}
+}
@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSort(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "find_sort")
+
+	tests := []struct {
+		name string
+		sort service.Sort
+		want []any
+	}{
+		{"default", nil, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"id desc", service.Sort{service.Desc(service.SortID)}, []any{9, 8, 7, 6, 5, 4, 3, 2, 1}},
+		{"title desc", service.Sort{service.Desc(service.SortTitle)}, []any{9, 8, 7, 6, 5, 4, 3, 2, 1}},
+		{"category", service.Sort{service.Asc(service.SortCategory)}, []any{3, 6, 9, 1, 4, 7, 2, 5, 8}},
+		{"done desc, category desc", service.Sort{
+			service.Desc(service.SortDone), service.Desc(service.SortCategory),
+		}, []any{2, 8, 4, 6, 5, 1, 7, 3, 9}},
+		{"category, id desc", service.Sort{
+			service.Asc(service.SortCategory), service.Desc(service.SortID),
+		}, []any{9, 6, 3, 7, 4, 1, 8, 5, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, todos, err := todoService.Find(ctx, nil, tt.sort, 0, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, Apply(todos, func(v any) any { return int(v.(service.Todo).ID) }))
+		})
+	}
+
+	t.Run("paged", func(t *testing.T) {
+		sort := service.Sort{service.Asc(service.SortCategory)}
+		var ids []any
+		for offset := int64(0); offset < 9; offset += 2 {
+			_, todos, err := todoService.Find(ctx, nil, sort, offset, 2)
+			assert.NoError(t, err)
+			ids = append(ids, Apply(todos, func(v any) any { return int(v.(service.Todo).ID) })...)
+		}
+		assert.Equal(t, []any{3, 6, 9, 1, 4, 7, 2, 5, 8}, ids)
+	})
+
+	t.Run("invalid field", func(t *testing.T) {
+		_, _, err := todoService.Find(ctx, nil, service.Sort{{Field: "title; DROP TABLE todo"}}, 0, 100)
+		assert.ErrorIs(t, err, service.ErrInvalidSort)
+	})
+}
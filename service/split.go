@@ -0,0 +1,163 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SplitStatements splits a migration script into statements, each ending
+// with its terminating semicolon when it has one. Semicolons inside string
+// literals, quoted identifiers, comments and CREATE TRIGGER ... BEGIN ... END
+// bodies do not end a statement. Statements holding only whitespace and
+// comments are dropped.
+func SplitStatements(script string) []string {
+	var stmts []string
+	s := splitter{src: script}
+	start := 0
+	for s.pos < len(s.src) {
+		if s.next() {
+			stmts = appendStatement(stmts, s.src[start:s.pos])
+			start = s.pos
+			s.reset()
+		}
+	}
+	return appendStatement(stmts, s.src[start:])
+}
+
+func appendStatement(stmts []string, stmt string) []string {
+	stmt = strings.TrimSpace(stmt)
+	if stripComments(stmt) == "" {
+		return stmts
+	}
+	return append(stmts, stmt)
+}
+
+// stripComments returns stmt without comments, trimmed.
+func stripComments(stmt string) string {
+	var b strings.Builder
+	s := splitter{src: stmt}
+	for s.pos < len(s.src) {
+		start := s.pos
+		comment := s.skipComment()
+		if !comment {
+			s.next()
+			b.WriteString(s.src[start:s.pos])
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+type splitter struct {
+	src string
+	pos int
+	// words holds the first keywords of the current statement, enough to
+	// recognise CREATE [TEMP|TEMPORARY] TRIGGER.
+	words   []string
+	trigger bool
+	depth   int
+}
+
+func (s *splitter) reset() {
+	s.words = s.words[:0]
+	s.trigger = false
+	s.depth = 0
+}
+
+// next consumes one token and reports whether it was a statement
+// terminating semicolon.
+func (s *splitter) next() bool {
+	if s.skipComment() {
+		return false
+	}
+	c := s.src[s.pos]
+	switch {
+	case c == '\'' || c == '"' || c == '`':
+		s.skipQuoted(c, c)
+	case c == '[':
+		s.skipQuoted('[', ']')
+	case c == ';':
+		s.pos++
+		return s.depth == 0
+	case isWordChar(c):
+		start := s.pos
+		for s.pos < len(s.src) && isWordChar(s.src[s.pos]) {
+			s.pos++
+		}
+		s.word(strings.ToUpper(s.src[start:s.pos]))
+	default:
+		s.pos++
+	}
+	return false
+}
+
+// skipComment consumes a -- or /* */ comment at pos, if any.
+func (s *splitter) skipComment() bool {
+	rest := s.src[s.pos:]
+	if strings.HasPrefix(rest, "--") {
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			s.pos += i + 1
+		} else {
+			s.pos = len(s.src)
+		}
+		return true
+	}
+	if strings.HasPrefix(rest, "/*") {
+		if i := strings.Index(rest[2:], "*/"); i >= 0 {
+			s.pos += i + 4
+		} else {
+			s.pos = len(s.src)
+		}
+		return true
+	}
+	return false
+}
+
+// skipQuoted consumes a quoted literal or identifier; a doubled closing
+// quote is an escaped quote.
+func (s *splitter) skipQuoted(open, close byte) {
+	s.pos++
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		s.pos++
+		if c == close {
+			if open != close || s.pos >= len(s.src) || s.src[s.pos] != close {
+				return
+			}
+			s.pos++
+		}
+	}
+}
+
+func (s *splitter) word(w string) {
+	if len(s.words) < 3 {
+		s.words = append(s.words, w)
+		if len(s.words) >= 2 && s.words[0] == "CREATE" {
+			switch {
+			case s.words[1] == "TRIGGER":
+				s.trigger = true
+			case len(s.words) == 3 && (s.words[1] == "TEMP" || s.words[1] == "TEMPORARY") && s.words[2] == "TRIGGER":
+				s.trigger = true
+			}
+		}
+		return
+	}
+	if !s.trigger {
+		return
+	}
+	switch w {
+	case "BEGIN":
+		s.depth++
+	case "CASE":
+		if s.depth > 0 {
+			s.depth++
+		}
+	case "END":
+		if s.depth > 0 {
+			s.depth--
+		}
+	}
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "empty",
+			script: "  \n\t\n",
+			want:   nil,
+		},
+		{
+			name:   "single line statements",
+			script: "CREATE TABLE a (id INTEGER); CREATE TABLE b (id INTEGER);",
+			want:   []string{"CREATE TABLE a (id INTEGER);", "CREATE TABLE b (id INTEGER);"},
+		},
+		{
+			name:   "multi line statement",
+			script: "CREATE TABLE a (\n  id INTEGER,\n  name TEXT\n);\n",
+			want:   []string{"CREATE TABLE a (\n  id INTEGER,\n  name TEXT\n);"},
+		},
+		{
+			name:   "missing final semicolon",
+			script: "DELETE FROM a;\nDELETE FROM b\n",
+			want:   []string{"DELETE FROM a;", "DELETE FROM b"},
+		},
+		{
+			name:   "semicolon in string",
+			script: "INSERT INTO a (name) VALUES ('x; y');\nINSERT INTO a (name) VALUES ('it''s; ok');",
+			want: []string{
+				"INSERT INTO a (name) VALUES ('x; y');",
+				"INSERT INTO a (name) VALUES ('it''s; ok');",
+			},
+		},
+		{
+			name:   "semicolon in quoted identifiers",
+			script: "CREATE TABLE \"a;b\" (`c;d` INTEGER, [e;f] TEXT);",
+			want:   []string{"CREATE TABLE \"a;b\" (`c;d` INTEGER, [e;f] TEXT);"},
+		},
+		{
+			name:   "line comments",
+			script: "-- create a; not here\nCREATE TABLE a (id INTEGER); -- trailing; comment\n-- only comment;\n",
+			want:   []string{"-- create a; not here\nCREATE TABLE a (id INTEGER);"},
+		},
+		{
+			name:   "block comments",
+			script: "/* header; */\nCREATE TABLE a (id /* ; */ INTEGER);\n/* footer; */",
+			want:   []string{"/* header; */\nCREATE TABLE a (id /* ; */ INTEGER);"},
+		},
+		{
+			name: "trigger",
+			script: `CREATE TRIGGER todo_audit AFTER UPDATE ON todo
+BEGIN
+  INSERT INTO todo_log (todo_id, note) VALUES (new.id, 'update; done');
+  UPDATE todo_stat SET total = total + 1;
+END;
+CREATE INDEX todo_title ON todo (title);`,
+			want: []string{
+				`CREATE TRIGGER todo_audit AFTER UPDATE ON todo
+BEGIN
+  INSERT INTO todo_log (todo_id, note) VALUES (new.id, 'update; done');
+  UPDATE todo_stat SET total = total + 1;
+END;`,
+				"CREATE INDEX todo_title ON todo (title);",
+			},
+		},
+		{
+			name: "temp trigger with case",
+			script: `create temp trigger t after insert on a when new.id > 0 begin
+  update b set v = case when new.id > 1 then 'x;' else 'y' end;
+  delete from c;
+end; select 1;`,
+			want: []string{
+				`create temp trigger t after insert on a when new.id > 0 begin
+  update b set v = case when new.id > 1 then 'x;' else 'y' end;
+  delete from c;
+end;`,
+				"select 1;",
+			},
+		},
+		{
+			name:   "transaction statements",
+			script: "BEGIN; INSERT INTO a VALUES (1); END;",
+			want:   []string{"BEGIN;", "INSERT INTO a VALUES (1);", "END;"},
+		},
+		{
+			name:   "pragma",
+			script: "PRAGMA foreign_keys = ON;\nPRAGMA integrity_check;\n",
+			want:   []string{"PRAGMA foreign_keys = ON;", "PRAGMA integrity_check;"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, service.SplitStatements(tt.script))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package service
+
+// StatsGroup names what Stats groups todos by. Backends reject any group they
+// do not know with ErrInvalidGroup.
+type StatsGroup string
+
+const (
+	// StatsAll counts every matching todo in a single TodoStats.
+	StatsAll        StatsGroup = ""
+	StatsByCategory StatsGroup = "category"
+	// StatsByDueDate groups by the UTC date todos are due, as 2006-01-02.
+	StatsByDueDate StatsGroup = "due_date"
+	// StatsByCompletedDate groups by the UTC date todos were completed.
+	StatsByCompletedDate StatsGroup = "completed_date"
+)
+
+// TodoStats counts the todos of one group. Key is the group's value, the
+// category name for StatsByCategory, and empty for StatsAll and for todos
+// without the date grouped by. StatsByCategory groups by CategoryID, so
+// categories sharing a name stay apart.
+type TodoStats struct {
+	Key        string `json:"key,omitempty"`
+	CategoryID int64  `json:"category_id,omitempty"`
+	Total      int64  `json:"total"`
+	Done       int64  `json:"done"`
+	Open       int64  `json:"open"`
+}
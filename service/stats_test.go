@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "stats")
+
+	stats, err := todoService.Stats(ctx, nil, service.StatsAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []service.TodoStats{{Total: 9, Done: 4, Open: 5}}, stats)
+
+	stats, err = todoService.Stats(ctx, nil, service.StatsByCategory)
+	assert.NoError(t, err)
+	assert.Equal(t, []service.TodoStats{
+		{Key: "errand", CategoryID: 3, Total: 3, Done: 1, Open: 2},
+		{Key: "home", CategoryID: 1, Total: 3, Done: 1, Open: 2},
+		{Key: "work", CategoryID: 2, Total: 3, Done: 2, Open: 1},
+	}, stats)
+
+	filter := todoService.Filter()
+	filter.ID().LessOrEqual(4)
+	stats, err = todoService.Stats(ctx, filter, service.StatsByCategory)
+	assert.NoError(t, err)
+	assert.Equal(t, []service.TodoStats{
+		{Key: "errand", CategoryID: 3, Total: 1, Done: 0, Open: 1},
+		{Key: "home", CategoryID: 1, Total: 2, Done: 1, Open: 1},
+		{Key: "work", CategoryID: 2, Total: 1, Done: 1, Open: 0},
+	}, stats)
+
+	filter = todoService.Filter()
+	filter.Title().Equal("none")
+	stats, err = todoService.Stats(ctx, filter, service.StatsAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []service.TodoStats{{}}, stats)
+
+	stats, err = todoService.Stats(ctx, filter, service.StatsByCategory)
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+
+	t.Run("same name", func(t *testing.T) {
+		ids, err := todoService.CreateCategory(ctx, []service.TodoCategory{{Name: "home"}})
+		assert.NoError(t, err)
+		_, err = todoService.Create(ctx, []service.Todo{{Title: "other home", Category: service.TodoCategory{ID: ids[0]}}})
+		assert.NoError(t, err)
+		filter := todoService.Filter()
+		filter.Category().Equal("home")
+		stats, err := todoService.Stats(ctx, filter, service.StatsByCategory)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.TodoStats{
+			{Key: "home", CategoryID: 1, Total: 3, Done: 1, Open: 2},
+			{Key: "home", CategoryID: ids[0], Total: 1, Done: 0, Open: 1},
+		}, stats)
+	})
+
+	_, err = todoService.Stats(ctx, nil, "owner")
+	assert.ErrorIs(t, err, service.ErrInvalidGroup)
+}
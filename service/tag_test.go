@@ -0,0 +1,210 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	service_impl "github.com/senomas/gotodo_service_sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "tags")
+
+	ids, err := todoService.CreateTag(ctx, []service.Tag{{Name: "urgent"}, {Name: "shop"}, {Name: "later"}})
+	assert.NoError(t, err)
+	urgent := service.Tag{ID: ids[0], Name: "urgent"}
+	shop := service.Tag{ID: ids[1], Name: "shop"}
+	later := service.Tag{ID: ids[2], Name: "later"}
+
+	set := map[int64][]service.Tag{
+		1: {urgent, shop, urgent},
+		2: {shop},
+		3: {later},
+		4: {urgent},
+	}
+	todos := []service.Todo{}
+	for id, tags := range set {
+		todo, err := todoService.Get(ctx, id)
+		assert.NoError(t, err)
+		todo.Tags = tags
+		todos = append(todos, todo)
+	}
+	_, err = todoService.Update(ctx, todos)
+	assert.NoError(t, err)
+
+	todo, err := todoService.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []service.Tag{shop, urgent}, todo.Tags)
+	todo, err = todoService.Get(ctx, 5)
+	assert.NoError(t, err)
+	assert.Nil(t, todo.Tags)
+
+	_, found, err := todoService.Find(ctx, nil, nil, 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{[]service.Tag{shop, urgent}, []service.Tag{shop}, []service.Tag{later}, []service.Tag{urgent},
+		[]service.Tag(nil), []service.Tag(nil), []service.Tag(nil), []service.Tag(nil), []service.Tag(nil)},
+		Apply(found, func(v any) any { return v.(service.Todo).Tags }))
+
+	page, err := todoService.FindPage(ctx, nil, nil, "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{[]service.Tag{shop, urgent}, []service.Tag{shop}},
+		Apply(page.Todos, func(v any) any { return v.(service.Todo).Tags }))
+
+	t.Run("create", func(t *testing.T) {
+		ids, err := todoService.Create(ctx, []service.Todo{
+			{Title: "todo 10", Category: service.TodoCategory{ID: 1}, Tags: []service.Tag{later}},
+		})
+		assert.NoError(t, err)
+		todo, err := todoService.Get(ctx, ids[0])
+		assert.NoError(t, err)
+		assert.Equal(t, []service.Tag{later}, todo.Tags)
+		assert.NoError(t, todoService.Delete(ctx, ids))
+
+		_, err = todoService.Create(ctx, []service.Todo{
+			{Title: "todo 11", Category: service.TodoCategory{ID: 1}, Tags: []service.Tag{{ID: 42}}},
+		})
+		assert.ErrorIs(t, err, service.ErrNoData)
+		assert.Equal(t, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}, findIDs(t, ctx, todoService, nil), "rolled back")
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			filter func(service.TodoFilter)
+			ids    []any
+		}{
+			{"has any", func(f service.TodoFilter) { f.Tags().HasAny([]string{"urgent", "later"}) }, []any{1, 3, 4}},
+			{"has all", func(f service.TodoFilter) { f.Tags().HasAll([]string{"urgent", "shop", "shop"}) }, []any{1}},
+			{"has none", func(f service.TodoFilter) { f.Tags().HasNone([]string{"urgent", "shop"}) }, []any{3, 5, 6, 7, 8, 9}},
+			{"has any empty", func(f service.TodoFilter) { f.Tags().HasAny(nil) }, []any{}},
+			{"has all empty", func(f service.TodoFilter) { f.Tags().HasAll(nil) }, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+			{"has all unknown", func(f service.TodoFilter) { f.Tags().HasAll([]string{"urgent", "none"}) }, []any{}},
+			{"combined", func(f service.TodoFilter) {
+				f.Or(f.Tags().HasAny([]string{"later"}), f.And(f.Tags().HasAny([]string{"shop"}), f.Done().Equal(true)))
+			}, []any{2, 3}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				filter := todoService.Filter()
+				tt.filter(filter)
+				assert.Equal(t, tt.ids, findIDs(t, ctx, todoService, filter))
+			})
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		tests := []struct {
+			query string
+			ids   []any
+		}{
+			{"tags:urgent,shop", []any{1}},
+			{"tags~urgent,later", []any{1, 3, 4}},
+			{"tags!:shop done:false", []any{3, 5, 7, 9}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				q, err := service.ParseFilterQuery(tt.query)
+				assert.NoError(t, err)
+				filter := todoService.Filter()
+				q.Apply(filter)
+				assert.Equal(t, tt.ids, findIDs(t, ctx, todoService, filter))
+			})
+		}
+		_, err := service.ParseFilterQuery("tags:urgent,")
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+		_, err = service.ParseFilterQuery("tags<urgent")
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+	})
+
+	t.Run("spec", func(t *testing.T) {
+		spec, err := service.ParseFilterSpec([]byte(`{"field": "tags", "op": "has_all", "value": ["shop"]}`))
+		assert.NoError(t, err)
+		filter := todoService.Filter()
+		_, err = spec.Apply(filter)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 2}, findIDs(t, ctx, todoService, filter))
+
+		_, err = service.ParseFilterSpec([]byte(`{"field": "tags", "op": "has_any", "value": "shop"}`))
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+		_, err = service.ParseFilterSpec([]byte(`{"field": "tags", "op": "eq", "value": ["shop"]}`))
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		todo, err := todoService.Get(ctx, 2)
+		assert.NoError(t, err)
+		todo.Tags = []service.Tag{later}
+		_, err = todoService.Update(ctx, []service.Todo{todo})
+		assert.NoError(t, err)
+		todo, err = todoService.Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.Tag{later}, todo.Tags)
+
+		todo.Tags = []service.Tag{{ID: 42}}
+		_, err = todoService.Update(ctx, []service.Todo{todo})
+		assert.ErrorIs(t, err, service.ErrNoData)
+		todo, err = todoService.Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.Tag{later}, todo.Tags, "rolled back")
+	})
+
+	t.Run("manage", func(t *testing.T) {
+		assert.NoError(t, todoService.UpdateTag(ctx, []service.Tag{{ID: later.ID, Name: "someday"}}))
+		err := todoService.UpdateTag(ctx, []service.Tag{{ID: 42, Name: "none"}})
+		assert.ErrorIs(t, err, service.ErrNoData)
+		_, err = todoService.CreateTag(ctx, []service.Tag{{Name: "shop"}})
+		assert.Error(t, err)
+
+		tags, err := todoService.ListTags(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.Tag{shop, {ID: later.ID, Name: "someday"}, urgent}, tags)
+
+		assert.ErrorIs(t, todoService.DeleteTag(ctx, []int64{urgent.ID, 42}), service.ErrNoData)
+		assert.NoError(t, todoService.DeleteTag(ctx, []int64{urgent.ID}))
+		todo, err := todoService.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.Tag{shop}, todo.Tags)
+		filter := todoService.Filter()
+		filter.Tags().HasAny([]string{"urgent"})
+		assert.Equal(t, []any{}, findIDs(t, ctx, todoService, filter))
+
+		assert.NoError(t, todoService.Delete(ctx, []int64{1}))
+		filter = todoService.Filter()
+		filter.Tags().HasAny([]string{"shop"})
+		assert.Equal(t, []any{}, findIDs(t, ctx, todoService, filter))
+	})
+}
+
+func TestTagsPlainDriver(t *testing.T) {
+	// foreign keys are off on plain sqlite3 connections
+	db, err := sql.Open("sqlite3", "file:tags_plain?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.NoError(t, todoService.Migrate(ctx))
+	_, err = todoService.CreateCategory(ctx, []service.TodoCategory{{Name: "home"}})
+	assert.NoError(t, err)
+	tags, err := todoService.CreateTag(ctx, []service.Tag{{Name: "urgent"}, {Name: "shop"}})
+	assert.NoError(t, err)
+	_, err = todoService.Create(ctx, []service.Todo{
+		{Title: "todo 1", Category: service.TodoCategory{ID: 1}, Tags: []service.Tag{{ID: tags[0]}, {ID: tags[1]}}},
+		{Title: "todo 2", Category: service.TodoCategory{ID: 1}, Tags: []service.Tag{{ID: tags[1]}}},
+	})
+	assert.NoError(t, err)
+	links := func() int64 {
+		var count int64
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM todo_tag").Scan(&count))
+		return count
+	}
+	assert.EqualValues(t, 3, links())
+
+	assert.NoError(t, todoService.Delete(ctx, []int64{1}))
+	assert.EqualValues(t, 1, links(), "unlinked with their todo")
+	assert.NoError(t, todoService.DeleteTag(ctx, []int64{tags[1]}))
+	assert.EqualValues(t, 0, links(), "unlinked with their tag")
+}
@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestamps(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "timestamps")
+
+	start := time.Now().UTC()
+	todo, err := todoService.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, start, todo.CreatedAt, time.Minute)
+	assert.Equal(t, todo.CreatedAt, todo.UpdatedAt)
+	assert.False(t, todo.CompletedAt.Valid)
+	created := todo.CreatedAt
+
+	done, err := todoService.Get(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, done.CompletedAt.Valid)
+	assert.Equal(t, done.CreatedAt, done.CompletedAt.Time)
+
+	time.Sleep(10 * time.Millisecond)
+	todo.Done = true
+	_, err = todoService.Update(ctx, []service.Todo{todo})
+	assert.NoError(t, err)
+	todo, err = todoService.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, created, todo.CreatedAt)
+	assert.True(t, todo.UpdatedAt.After(created))
+	assert.True(t, todo.CompletedAt.Valid)
+	assert.Equal(t, todo.UpdatedAt, todo.CompletedAt.Time)
+	completed := todo.CompletedAt
+
+	t.Run("completed kept while done", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		todo.Title = "todo 1 renamed"
+		_, err = todoService.Update(ctx, []service.Todo{todo})
+		assert.NoError(t, err)
+		todo, err = todoService.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, completed, todo.CompletedAt)
+		assert.True(t, todo.UpdatedAt.After(completed.Time))
+	})
+
+	t.Run("completed cleared when reopened", func(t *testing.T) {
+		open := false
+		filter := todoService.Filter()
+		filter.ID().Equal(2)
+		_, err = todoService.UpdateWhere(ctx, filter, service.TodoPatch{Done: &open}, false)
+		assert.NoError(t, err)
+		done, err = todoService.Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.False(t, done.CompletedAt.Valid)
+		assert.True(t, done.UpdatedAt.After(done.CreatedAt))
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		filter := todoService.Filter()
+		filter.CompletedAt().Within(-time.Hour)
+		assert.Equal(t, []any{1, 4, 6, 8}, findIDs(t, ctx, todoService, filter))
+
+		filter = todoService.Filter()
+		filter.UpdatedAt().After(created)
+		assert.Equal(t, []any{1, 2}, findIDs(t, ctx, todoService, filter))
+
+		q, err := service.ParseFilterQuery("created_at~-1h completed_at:null")
+		assert.NoError(t, err)
+		filter = todoService.Filter()
+		q.Apply(filter)
+		assert.Equal(t, []any{2, 3, 5, 7, 9}, findIDs(t, ctx, todoService, filter))
+	})
+
+	t.Run("sort", func(t *testing.T) {
+		_, todos, err := todoService.Find(ctx, nil, service.Sort{service.Desc(service.SortUpdated)}, 0, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{2, 1, 3}, Apply(todos, func(v any) any { return int(v.(service.Todo).ID) }))
+
+		sort := service.Sort{service.Asc(service.SortCompleted)}
+		_, todos, err = todoService.Find(ctx, nil, sort, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{2, 3, 5, 7, 9, 4, 6, 8, 1},
+			Apply(todos, func(v any) any { return int(v.(service.Todo).ID) }))
+
+		var ids []any
+		page := service.Page{}
+		for {
+			page, err = todoService.FindPage(ctx, nil, sort, page.Next, 4)
+			assert.NoError(t, err)
+			ids = append(ids, Apply(page.Todos, func(v any) any { return int(v.(service.Todo).ID) })...)
+			if page.Next == "" {
+				break
+			}
+		}
+		assert.Equal(t, []any{2, 3, 5, 7, 9, 4, 6, 8, 1}, ids)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats, err := todoService.Stats(ctx, nil, service.StatsByCompletedDate)
+		assert.NoError(t, err)
+		assert.Equal(t, []service.TodoStats{
+			{Key: "", Total: 5, Done: 0, Open: 5},
+			{Key: completed.Time.Format(time.DateOnly), Total: 4, Done: 4, Open: 0},
+		}, stats)
+	})
+}
@@ -3,14 +3,71 @@ package service
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"io"
+	"time"
 )
 
 type Todo struct {
-	Title       string         `json:"title"`
+	Title   string       `json:"title"`
+	Snippet string       `json:"snippet,omitempty"`
+	DueAt   sql.NullTime `json:"due_at"`
+	StartAt sql.NullTime `json:"start_at"`
+	// CreatedAt, UpdatedAt and CompletedAt are maintained by the service;
+	// CompletedAt is set when Done becomes true and cleared when it becomes
+	// false.
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
 	Description sql.NullString `json:"description"`
-	Category    TodoCategory   `json:"category"`
-	ID          int64          `json:"id"`
-	Done        bool           `json:"done"`
+	// ParentID makes the todo a subtask of another todo; deleting a todo
+	// deletes its subtasks.
+	ParentID sql.NullInt64 `json:"parent_id"`
+	Category TodoCategory  `json:"category"`
+	// Tags are saved by Create and replaced by Update.
+	Tags     []Tag    `json:"tags,omitempty"`
+	ID       int64    `json:"id"`
+	Priority Priority `json:"priority"`
+	Done     bool     `json:"done"`
+}
+
+// Priority ranks todos for triage, higher is more pressing.
+type Priority int64
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNone:
+		return "none"
+	case PriorityLow:
+		return "low"
+	case PriorityMedium:
+		return "medium"
+	case PriorityHigh:
+		return "high"
+	case PriorityUrgent:
+		return "urgent"
+	}
+	return fmt.Sprintf("Priority(%d)", int64(p))
+}
+
+// TodoPatch lists the fields UpdateWhere sets; nil fields are left as they
+// are.
+type TodoPatch struct {
+	Title       *string         `json:"title,omitempty"`
+	Description *sql.NullString `json:"description,omitempty"`
+	DueAt       *sql.NullTime   `json:"due_at,omitempty"`
+	StartAt     *sql.NullTime   `json:"start_at,omitempty"`
+	CategoryID  *int64          `json:"category_id,omitempty"`
+	Priority    *Priority       `json:"priority,omitempty"`
+	Done        *bool           `json:"done,omitempty"`
 }
 
 type TodoCategory struct {
@@ -18,31 +75,134 @@ type TodoCategory struct {
 	ID   int64  `json:"id"`
 }
 
-type TodoFilter interface {
+// Tag is a label todos share across categories.
+type Tag struct {
+	Name string `json:"name"`
+	ID   int64  `json:"id"`
+}
+
+// DeletePolicy is what DeleteCategory does with the todos of a category.
+type DeletePolicy int
+
+const (
+	// DeleteRestrict fails with a *CategoryInUseError when todos remain.
+	DeleteRestrict DeletePolicy = iota
+	// DeleteCascade deletes the todos with their category.
+	DeleteCascade
+	// DeleteReassign moves the todos to another category.
+	DeleteReassign
+)
+
+// CategoryInUseError is returned by DeleteCategory with DeleteRestrict when
+// Todos todos still belong to the categories.
+type CategoryInUseError struct {
+	Todos int64
+}
+
+func (e *CategoryInUseError) Error() string {
+	return fmt.Sprintf("%v: %d todos", ErrCategoryInUse, e.Todos)
+}
+
+func (e *CategoryInUseError) Unwrap() error {
+	return ErrCategoryInUse
+}
+
+// FilterBuilder creates filters on todo fields. The filters it returns are
+// only meaningful to the FilterBuilder that created them.
+type FilterBuilder interface {
+	ID() FilterInt
 	Title() FilterString
 	Description() FilterString
 	Category() FilterString
 	CategoryID() FilterInt
+	Priority() FilterInt
 	Done() FilterBool
+	// Tags filters on tag names.
+	Tags() FilterTags
+	DueAt() FilterTime
+	StartAt() FilterTime
+	CreatedAt() FilterTime
+	UpdatedAt() FilterTime
+	CompletedAt() FilterTime
+	// Overdue matches open todos due before now, taken when the query runs.
+	Overdue() Filter
+	// TopLevel matches todos that are not a subtask.
+	TopLevel() Filter
+	// Search matches todos whose title or description contain every word of
+	// query, each as a prefix, and every "quoted phrase" as is. Find fills
+	// Todo.Snippet with the matching excerpt where the backend supports it.
+	Search(query string) Filter
+
+	// And, Or and Not group filters created by this FilterBuilder; grouped
+	// filters are no longer ANDed at the top level. Groups nest. Not matches
+	// every todo its filter does not, including those with a NULL field.
+	And(...Filter) Filter
+	Or(...Filter) Filter
+	Not(Filter) Filter
+}
 
-	Generate(QueryBuilder)
+// TodoFilter is a FilterBuilder that records its filters as a FilterSpec,
+// which every backend compiles itself. See NewTodoFilter.
+type TodoFilter interface {
+	FilterBuilder
+	// Spec returns the filters created so far, ANDed.
+	Spec() FilterSpec
 }
 
 type TodoService interface {
 	Migrate(ctx context.Context) error
+	Rollback(ctx context.Context, version int64) error
+	MigrationStatus(ctx context.Context) ([]MigrationEntry, error)
+	MigrateDryRun(ctx context.Context, w io.Writer) error
 
 	CreateCategory(ctx context.Context, categories []TodoCategory) ([]int64, error)
+	// UpdateCategory renames categories, failing with ErrNoData when one does
+	// not exist.
 	UpdateCategory(ctx context.Context, categories []TodoCategory) error
-	DeleteCategory(ctx context.Context, ids []int64) error
+	// DeleteCategory deletes categories, handling their todos as policy says,
+	// and fails with ErrNoData when one does not exist. reassignTo is the
+	// category todos move to with DeleteReassign.
+	DeleteCategory(ctx context.Context, ids []int64, policy DeletePolicy, reassignTo int64) error
+
+	// CreateTag, UpdateTag and DeleteTag manage tags; deleting a tag removes
+	// it from its todos. UpdateTag and DeleteTag fail with ErrNoData when a
+	// tag does not exist. ListTags returns every tag ordered by name.
+	CreateTag(ctx context.Context, tags []Tag) ([]int64, error)
+	UpdateTag(ctx context.Context, tags []Tag) error
+	DeleteTag(ctx context.Context, ids []int64) error
+	ListTags(ctx context.Context) ([]Tag, error)
 
 	Create(ctx context.Context, todos []Todo) ([]int64, error)
 	Update(ctx context.Context, todos []Todo) (int64, error)
+	// Delete fails with ErrNoData, deleting nothing, when a todo does not
+	// exist.
 	Delete(ctx context.Context, ids []int64) error
 
 	Get(ctx context.Context, id int64) (Todo, error)
+	// Children returns the direct subtasks of todo id, ordered by id. Tree
+	// returns todo rootID with all its subtasks, failing with ErrNoData when
+	// it does not exist.
+	Children(ctx context.Context, id int64) ([]Todo, error)
+	Tree(ctx context.Context, rootID int64) (TodoNode, error)
 
 	Filter() TodoFilter
 	Find(
-		ctx context.Context, filter TodoFilter, offset int64, limit int,
+		ctx context.Context, filter TodoFilter, sort Sort, offset int64, limit int,
 	) (int64, []Todo, error)
+	// FindPage returns up to limit todos after cursor, which is either empty
+	// for the first page or the Next of the previous page for the same filter
+	// and sort.
+	FindPage(
+		ctx context.Context, filter TodoFilter, sort Sort, cursor string, limit int,
+	) (Page, error)
+	// Stats counts the todos matching filter per group, ordered by key.
+	// StatsAll always returns one TodoStats, even when nothing matches.
+	Stats(ctx context.Context, filter TodoFilter, groupBy StatsGroup) ([]TodoStats, error)
+
+	// UpdateWhere applies patch to every todo matching filter and DeleteWhere
+	// deletes them, both in a single statement, returning the number of
+	// todos affected. A filter without conditions fails with ErrEmptyFilter
+	// unless all is set.
+	UpdateWhere(ctx context.Context, filter TodoFilter, patch TodoPatch, all bool) (int64, error)
+	DeleteWhere(ctx context.Context, filter TodoFilter, all bool) (int64, error)
 }
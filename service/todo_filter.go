@@ -0,0 +1,341 @@
+package service
+
+import "time"
+
+// NewTodoFilter returns a TodoFilter that is not tied to any backend: its
+// filters are FilterSpec nodes, so a filter built here, or its Spec sent
+// over the wire and applied to another NewTodoFilter, works with every
+// TodoService.
+func NewTodoFilter() TodoFilter {
+	return &specBuilder{}
+}
+
+// specBuilder collects the filters created through its field filters and
+// groups. Every filter is ANDed at the top level until it is passed to And,
+// Or or Not, which move it into their group.
+type specBuilder struct {
+	conds []*specFilter
+}
+
+// specFilter is a Filter created by a specBuilder.
+type specFilter struct {
+	spec FilterSpec
+}
+
+// Spec implements TodoFilter.
+func (b *specBuilder) Spec() FilterSpec {
+	switch len(b.conds) {
+	case 0:
+		return FilterSpec{}
+	case 1:
+		return b.conds[0].spec
+	}
+	specs := make([]FilterSpec, len(b.conds))
+	for i, c := range b.conds {
+		specs[i] = c.spec
+	}
+	return FilterSpec{And: specs}
+}
+
+// ID implements FilterBuilder.
+func (b *specBuilder) ID() FilterInt {
+	return &specFilterInt{builder: b, field: "id"}
+}
+
+// Title implements FilterBuilder.
+func (b *specBuilder) Title() FilterString {
+	return &specFilterString{builder: b, field: "title"}
+}
+
+// Description implements FilterBuilder.
+func (b *specBuilder) Description() FilterString {
+	return &specFilterString{builder: b, field: "description"}
+}
+
+// Category implements FilterBuilder.
+func (b *specBuilder) Category() FilterString {
+	return &specFilterString{builder: b, field: "category"}
+}
+
+// CategoryID implements FilterBuilder.
+func (b *specBuilder) CategoryID() FilterInt {
+	return &specFilterInt{builder: b, field: "category_id"}
+}
+
+// Done implements FilterBuilder.
+func (b *specBuilder) Done() FilterBool {
+	return &specFilterBool{builder: b, field: "done"}
+}
+
+// Priority implements FilterBuilder.
+func (b *specBuilder) Priority() FilterInt {
+	return &specFilterInt{builder: b, field: "priority"}
+}
+
+// Tags implements FilterBuilder.
+func (b *specBuilder) Tags() FilterTags {
+	return &specFilterTags{builder: b, field: "tags"}
+}
+
+// DueAt implements FilterBuilder.
+func (b *specBuilder) DueAt() FilterTime {
+	return &specFilterTime{builder: b, field: "due_at"}
+}
+
+// StartAt implements FilterBuilder.
+func (b *specBuilder) StartAt() FilterTime {
+	return &specFilterTime{builder: b, field: "start_at"}
+}
+
+// CreatedAt implements FilterBuilder.
+func (b *specBuilder) CreatedAt() FilterTime {
+	return &specFilterTime{builder: b, field: "created_at"}
+}
+
+// UpdatedAt implements FilterBuilder.
+func (b *specBuilder) UpdatedAt() FilterTime {
+	return &specFilterTime{builder: b, field: "updated_at"}
+}
+
+// CompletedAt implements FilterBuilder.
+func (b *specBuilder) CompletedAt() FilterTime {
+	return &specFilterTime{builder: b, field: "completed_at"}
+}
+
+// Overdue implements FilterBuilder.
+func (b *specBuilder) Overdue() Filter {
+	return b.add(FilterSpec{Field: "overdue", Op: "eq", Value: true})
+}
+
+// TopLevel implements FilterBuilder.
+func (b *specBuilder) TopLevel() Filter {
+	return b.add(FilterSpec{Field: "top_level", Op: "eq", Value: true})
+}
+
+// Search implements FilterBuilder.
+func (b *specBuilder) Search(query string) Filter {
+	return b.add(FilterSpec{Search: query})
+}
+
+// And implements FilterBuilder.
+func (b *specBuilder) And(filters ...Filter) Filter {
+	return b.add(FilterSpec{And: b.group(filters)})
+}
+
+// Or implements FilterBuilder.
+func (b *specBuilder) Or(filters ...Filter) Filter {
+	return b.add(FilterSpec{Or: b.group(filters)})
+}
+
+// Not implements FilterBuilder.
+func (b *specBuilder) Not(filter Filter) Filter {
+	spec := b.group([]Filter{filter})[0]
+	return b.add(FilterSpec{Not: &spec})
+}
+
+// group removes filters from the top level and returns their specs. A
+// filter from another builder becomes an empty spec, which adds no condition.
+func (b *specBuilder) group(filters []Filter) []FilterSpec {
+	specs := make([]FilterSpec, len(filters))
+	for i, filter := range filters {
+		if c, ok := filter.(*specFilter); ok {
+			b.remove(c)
+			specs[i] = c.spec
+		}
+	}
+	return specs
+}
+
+func (b *specBuilder) add(spec FilterSpec) Filter {
+	c := &specFilter{spec: spec}
+	b.conds = append(b.conds, c)
+	return c
+}
+
+func (b *specBuilder) remove(filter *specFilter) {
+	for i, c := range b.conds {
+		if c == filter {
+			b.conds = append(b.conds[:i], b.conds[i+1:]...)
+			return
+		}
+	}
+}
+
+type specFilterString struct {
+	builder *specBuilder
+	field   string
+}
+
+func (f *specFilterString) cond(op string, v any) Filter {
+	return f.builder.add(FilterSpec{Field: f.field, Op: op, Value: v})
+}
+
+// Equal implements FilterString.
+func (f *specFilterString) Equal(v string) Filter {
+	return f.cond("eq", v)
+}
+
+// NotEqual implements FilterString.
+func (f *specFilterString) NotEqual(v string) Filter {
+	return f.cond("ne", v)
+}
+
+// Like implements FilterString.
+func (f *specFilterString) Like(v string) Filter {
+	return f.cond("like", v)
+}
+
+// NotLike implements FilterString.
+func (f *specFilterString) NotLike(v string) Filter {
+	return f.cond("not_like", v)
+}
+
+// Contains implements FilterString.
+func (f *specFilterString) Contains(v string) Filter {
+	return f.cond("contains", v)
+}
+
+// NotContains implements FilterString.
+func (f *specFilterString) NotContains(v string) Filter {
+	return f.cond("not_contains", v)
+}
+
+// In implements FilterString.
+func (f *specFilterString) In(v []string) Filter {
+	values := make([]any, len(v))
+	for i, s := range v {
+		values[i] = s
+	}
+	return f.cond("in", values)
+}
+
+// IsNull implements FilterString.
+func (f *specFilterString) IsNull() Filter {
+	return f.cond("is_null", nil)
+}
+
+// IsNotNull implements FilterString.
+func (f *specFilterString) IsNotNull() Filter {
+	return f.cond("is_not_null", nil)
+}
+
+type specFilterInt struct {
+	builder *specBuilder
+	field   string
+}
+
+func (f *specFilterInt) cond(op string, v any) Filter {
+	return f.builder.add(FilterSpec{Field: f.field, Op: op, Value: v})
+}
+
+// Equal implements FilterInt.
+func (f *specFilterInt) Equal(v int64) Filter {
+	return f.cond("eq", v)
+}
+
+// NotEqual implements FilterInt.
+func (f *specFilterInt) NotEqual(v int64) Filter {
+	return f.cond("ne", v)
+}
+
+// Less implements FilterInt.
+func (f *specFilterInt) Less(v int64) Filter {
+	return f.cond("lt", v)
+}
+
+// LessOrEqual implements FilterInt.
+func (f *specFilterInt) LessOrEqual(v int64) Filter {
+	return f.cond("le", v)
+}
+
+// Greater implements FilterInt.
+func (f *specFilterInt) Greater(v int64) Filter {
+	return f.cond("gt", v)
+}
+
+// GreaterOrEqual implements FilterInt.
+func (f *specFilterInt) GreaterOrEqual(v int64) Filter {
+	return f.cond("ge", v)
+}
+
+// Between implements FilterInt.
+func (f *specFilterInt) Between(v1, v2 int64) Filter {
+	return f.cond("between", []any{v1, v2})
+}
+
+type specFilterBool struct {
+	builder *specBuilder
+	field   string
+}
+
+// Equal implements FilterBool.
+func (f *specFilterBool) Equal(v bool) Filter {
+	return f.builder.add(FilterSpec{Field: f.field, Op: "eq", Value: v})
+}
+
+type specFilterTags struct {
+	builder *specBuilder
+	field   string
+}
+
+func (f *specFilterTags) cond(op string, names []string) Filter {
+	values := make([]any, len(names))
+	for i, name := range names {
+		values[i] = name
+	}
+	return f.builder.add(FilterSpec{Field: f.field, Op: op, Value: values})
+}
+
+// HasAny implements FilterTags.
+func (f *specFilterTags) HasAny(names []string) Filter {
+	return f.cond("has_any", names)
+}
+
+// HasAll implements FilterTags.
+func (f *specFilterTags) HasAll(names []string) Filter {
+	return f.cond("has_all", names)
+}
+
+// HasNone implements FilterTags.
+func (f *specFilterTags) HasNone(names []string) Filter {
+	return f.cond("has_none", names)
+}
+
+type specFilterTime struct {
+	builder *specBuilder
+	field   string
+}
+
+func (f *specFilterTime) cond(op string, v any) Filter {
+	return f.builder.add(FilterSpec{Field: f.field, Op: op, Value: v})
+}
+
+// Before implements FilterTime.
+func (f *specFilterTime) Before(v time.Time) Filter {
+	return f.cond("lt", v)
+}
+
+// After implements FilterTime.
+func (f *specFilterTime) After(v time.Time) Filter {
+	return f.cond("gt", v)
+}
+
+// Between implements FilterTime.
+func (f *specFilterTime) Between(v1, v2 time.Time) Filter {
+	return f.cond("between", []any{v1, v2})
+}
+
+// Within implements FilterTime.
+func (f *specFilterTime) Within(d time.Duration) Filter {
+	return f.cond("within", d.String())
+}
+
+// IsNull implements FilterTime.
+func (f *specFilterTime) IsNull() Filter {
+	return f.cond("is_null", nil)
+}
+
+// IsNotNull implements FilterTime.
+func (f *specFilterTime) IsNotNull() Filter {
+	return f.cond("is_not_null", nil)
+}
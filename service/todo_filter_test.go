@@ -0,0 +1,51 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	"github.com/stretchr/testify/assert"
+)
+
+// specFilter is a TodoFilter from outside any backend.
+type specFilter struct {
+	service.TodoFilter
+	spec service.FilterSpec
+}
+
+func (f specFilter) Spec() service.FilterSpec {
+	return f.spec
+}
+
+func TestNewTodoFilter(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "todo_filter")
+
+	filter := service.NewTodoFilter()
+	filter.Done().Equal(false)
+	filter.Or(filter.Category().Equal("errand"), filter.Not(filter.ID().Greater(1)))
+	assert.Equal(t, service.FilterSpec{And: []service.FilterSpec{
+		{Field: "done", Op: "eq", Value: false},
+		{Or: []service.FilterSpec{
+			{Field: "category", Op: "eq", Value: "errand"},
+			{Not: &service.FilterSpec{Field: "id", Op: "gt", Value: int64(1)}},
+		}},
+	}}, filter.Spec())
+	assert.Equal(t, []any{1, 3, 9}, findIDs(t, ctx, todoService, filter))
+
+	t.Run("over the wire", func(t *testing.T) {
+		data, err := json.Marshal(filter.Spec())
+		assert.NoError(t, err)
+		spec, err := service.ParseFilterSpec(data)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 3, 9}, findIDs(t, ctx, todoService, specFilter{spec: spec}))
+	})
+
+	t.Run("invalid spec", func(t *testing.T) {
+		invalid := specFilter{spec: service.FilterSpec{Field: "owner", Op: "eq", Value: "me"}}
+		_, _, err := todoService.Find(ctx, invalid, nil, 0, 10)
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+		_, err = todoService.FindPage(ctx, invalid, nil, "", 10)
+		assert.ErrorIs(t, err, service.ErrInvalidFilter)
+	})
+}
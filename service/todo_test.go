@@ -8,6 +8,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	service "github.com/senomas/gotodo_service"
@@ -27,7 +28,6 @@ func init() {
 	}
 	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
 	slog.SetDefault(log)
-	os.Setenv("MIGRATION_PATH", "")
 }
 
 func TestCrud(t *testing.T) {
@@ -76,6 +76,9 @@ func TestCrud(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
 		todo, err := todoService.Get(ctx, 1)
 		assert.NoError(t, err)
+		assert.False(t, todo.CreatedAt.IsZero())
+		assert.Equal(t, todo.CreatedAt, todo.UpdatedAt)
+		todo.CreatedAt, todo.UpdatedAt = time.Time{}, time.Time{}
 		assert.EqualValues(t, service.Todo{
 			ID:    1,
 			Title: "todo 1",
@@ -116,7 +119,7 @@ func TestCrud(t *testing.T) {
 
 	t.Run("Find", func(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
-		total, todos, err := todoService.Find(ctx, nil, 0, 10)
+		total, todos, err := todoService.Find(ctx, nil, nil, 0, 10)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 3, total)
 		assert.EqualValues(t,
@@ -150,7 +153,7 @@ func TestCrud(t *testing.T) {
 
 	t.Run("Find updated", func(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
-		total, todos, err := todoService.Find(ctx, nil, 0, 10)
+		total, todos, err := todoService.Find(ctx, nil, nil, 0, 10)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 3, total)
 		assert.EqualValues(t,
@@ -186,7 +189,7 @@ func TestCrud(t *testing.T) {
 
 	t.Run("Find with offset limit", func(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
-		total, todos, err := todoService.Find(ctx, nil, 4, 5)
+		total, todos, err := todoService.Find(ctx, nil, nil, 4, 5)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 113, total)
 		assert.EqualValues(t,
@@ -204,7 +207,7 @@ func TestCrud(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
 		filter := todoService.Filter()
 		filter.Title().Like("%11%")
-		total, todos, err := todoService.Find(ctx, filter, 0, 2)
+		total, todos, err := todoService.Find(ctx, filter, nil, 0, 2)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 5, total)
 		assert.EqualValues(t,
@@ -222,7 +225,7 @@ func TestCrud(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
 		filter := todoService.Filter()
 		filter.Category().Equal("category 2")
-		total, todos, err := todoService.Find(ctx, filter, 0, 2)
+		total, todos, err := todoService.Find(ctx, filter, nil, 0, 2)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 56, total)
 		assert.EqualValues(t,
@@ -241,7 +244,7 @@ func TestCrud(t *testing.T) {
 		filter := todoService.Filter()
 		filter.Title().Like("%11%")
 		filter.Category().Equal("category 2")
-		total, todos, err := todoService.Find(ctx, filter, 0, 2)
+		total, todos, err := todoService.Find(ctx, filter, nil, 0, 2)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 4, total)
 		assert.EqualValues(t,
@@ -259,7 +262,7 @@ func TestCrud(t *testing.T) {
 		todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
 		filter := todoService.Filter()
 		filter.Category().In([]string{"category 2", `"safe" in`})
-		total, todos, err := todoService.Find(ctx, filter, 0, 2)
+		total, todos, err := todoService.Find(ctx, filter, nil, 0, 2)
 		assert.NoError(t, err)
 		assert.EqualValues(t, 56, total)
 		assert.EqualValues(t,
@@ -0,0 +1,15 @@
+package service
+
+// TodoNode is a todo with its subtasks, as returned by Tree.
+type TodoNode struct {
+	Children []TodoNode `json:"children,omitempty"`
+	Todo     Todo       `json:"todo"`
+	// Progress counts the subtasks at every depth below the todo.
+	Progress Progress `json:"progress"`
+}
+
+// Progress is how many of a set of todos are done.
+type Progress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	service "github.com/senomas/gotodo_service"
+	service_impl "github.com/senomas/gotodo_service_sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	ctx, todoService := setupFilterTodos(t, "tree")
+
+	parents := map[int64]int64{2: 1, 3: 1, 4: 2, 5: 2, 6: 4}
+	for _, id := range []int64{2, 3, 4, 5, 6} {
+		todo, err := todoService.Get(ctx, id)
+		assert.NoError(t, err)
+		todo.ParentID = sql.NullInt64{Int64: parents[id], Valid: true}
+		_, err = todoService.Update(ctx, []service.Todo{todo})
+		assert.NoError(t, err)
+	}
+
+	todo, err := todoService.Get(ctx, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, sql.NullInt64{Int64: 2, Valid: true}, todo.ParentID)
+
+	t.Run("children", func(t *testing.T) {
+		children, err := todoService.Children(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{2, 3}, Apply(children, func(v any) any { return int(v.(service.Todo).ID) }))
+		children, err = todoService.Children(ctx, 9)
+		assert.NoError(t, err)
+		assert.Empty(t, children)
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		root, err := todoService.Tree(ctx, 1)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, root.Todo.ID)
+		assert.Equal(t, service.Progress{Done: 3, Total: 5}, root.Progress)
+		assert.Len(t, root.Children, 2)
+		node := root.Children[0]
+		assert.EqualValues(t, 2, node.Todo.ID)
+		assert.Equal(t, service.Progress{Done: 2, Total: 3}, node.Progress)
+		assert.Equal(t, []any{4, 5}, Apply(node.Children, func(v any) any { return int(v.(service.TodoNode).Todo.ID) }))
+		assert.Equal(t, service.Progress{Done: 1, Total: 1}, node.Children[0].Progress)
+		assert.Equal(t, service.Progress{}, root.Children[1].Progress)
+		assert.Empty(t, root.Children[1].Children)
+
+		leaf, err := todoService.Tree(ctx, 6)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 6, leaf.Todo.ID)
+		assert.Empty(t, leaf.Children)
+
+		_, err = todoService.Tree(ctx, 42)
+		assert.ErrorIs(t, err, service.ErrNoData)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		todo, err := todoService.Get(ctx, 1)
+		assert.NoError(t, err)
+		for _, parent := range []int64{1, 2, 6} {
+			todo.ParentID = sql.NullInt64{Int64: parent, Valid: true}
+			_, err = todoService.Update(ctx, []service.Todo{todo})
+			assert.ErrorIs(t, err, service.ErrParentCycle, "parent %d", parent)
+		}
+		todo.ParentID = sql.NullInt64{Int64: 42, Valid: true}
+		_, err = todoService.Update(ctx, []service.Todo{todo})
+		assert.ErrorIs(t, err, service.ErrNoData)
+
+		todo7, err := todoService.Get(ctx, 7)
+		assert.NoError(t, err)
+		todo8, err := todoService.Get(ctx, 8)
+		assert.NoError(t, err)
+		todo7.ParentID = sql.NullInt64{Int64: 8, Valid: true}
+		todo8.ParentID = sql.NullInt64{Int64: 7, Valid: true}
+		_, err = todoService.Update(ctx, []service.Todo{todo7, todo8})
+		assert.ErrorIs(t, err, service.ErrParentCycle)
+		todo7, err = todoService.Get(ctx, 7)
+		assert.NoError(t, err)
+		assert.False(t, todo7.ParentID.Valid, "rolled back")
+
+		_, err = todoService.Create(ctx, []service.Todo{
+			{Title: "orphan", Category: service.TodoCategory{ID: 1}, ParentID: sql.NullInt64{Int64: 42, Valid: true}},
+		})
+		assert.ErrorIs(t, err, service.ErrNoData)
+	})
+
+	t.Run("top level", func(t *testing.T) {
+		filter := todoService.Filter()
+		filter.TopLevel()
+		assert.Equal(t, []any{1, 7, 8, 9}, findIDs(t, ctx, todoService, filter))
+
+		q, err := service.ParseFilterQuery("top_level!:true done:true")
+		assert.NoError(t, err)
+		filter = todoService.Filter()
+		q.Apply(filter)
+		assert.Equal(t, []any{2, 4, 6}, findIDs(t, ctx, todoService, filter))
+
+		spec, err := service.ParseFilterSpec([]byte(`{"field": "top_level", "op": "eq", "value": true}`))
+		assert.NoError(t, err)
+		filter = todoService.Filter()
+		_, err = spec.Apply(filter)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 7, 8, 9}, findIDs(t, ctx, todoService, filter))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		ids, err := todoService.Create(ctx, []service.Todo{
+			{Title: "step", Category: service.TodoCategory{ID: 1}, ParentID: sql.NullInt64{Int64: 6, Valid: true}},
+		})
+		assert.NoError(t, err)
+		root, err := todoService.Tree(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, service.Progress{Done: 3, Total: 6}, root.Progress)
+
+		assert.NoError(t, todoService.Delete(ctx, []int64{2}))
+		assert.Equal(t, []any{1, 3, 7, 8, 9}, findIDs(t, ctx, todoService, nil))
+		_, err = todoService.Get(ctx, ids[0])
+		assert.ErrorIs(t, err, service.ErrNoData)
+		root, err = todoService.Tree(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, service.Progress{Total: 1}, root.Progress)
+
+		// 3 is gone with 1 by the time it would be deleted
+		assert.NoError(t, todoService.Delete(ctx, []int64{1, 3}))
+		assert.Equal(t, []any{7, 8, 9}, findIDs(t, ctx, todoService, nil))
+	})
+}
+
+func TestTreePlainDriver(t *testing.T) {
+	// foreign keys are off on plain sqlite3 connections
+	db, err := sql.Open("sqlite3", "file:tree_plain?mode=memory&cache=shared")
+	assert.NoError(t, err, "failed to open db")
+	defer db.Close()
+
+	ctx := service_impl.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	assert.NoError(t, todoService.Migrate(ctx))
+	_, err = todoService.CreateCategory(ctx, []service.TodoCategory{{Name: "home"}})
+	assert.NoError(t, err)
+	parent := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	for _, todo := range []service.Todo{
+		{Title: "todo 1", Category: service.TodoCategory{ID: 1}},
+		{Title: "todo 2", Category: service.TodoCategory{ID: 1}, ParentID: parent(1)},
+		{Title: "todo 3", Category: service.TodoCategory{ID: 1}, ParentID: parent(2)},
+		{Title: "todo 4", Category: service.TodoCategory{ID: 1}},
+	} {
+		_, err := todoService.Create(ctx, []service.Todo{todo})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, todoService.Delete(ctx, []int64{1}))
+	assert.Equal(t, []any{4}, findIDs(t, ctx, todoService, nil), "subtree deleted")
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// UpdateWhere implements service.TodoService.
+func (TodoService) UpdateWhere(
+	ctx context.Context, filter service.TodoFilter, patch service.TodoPatch, all bool,
+) (int64, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		var qrySet service.QueryBuilder = &QueryBuilder{prefix: "SET ", sep: ", "}
+		if patch.Title != nil {
+			qrySet.AddTextParam("title = ?", *patch.Title)
+		}
+		if patch.Description != nil {
+			qrySet.AddTextParam("description = ?", *patch.Description)
+		}
+		if patch.CategoryID != nil {
+			qrySet.AddTextParam("category_id = ?", *patch.CategoryID)
+		}
+		if patch.Priority != nil {
+			qrySet.AddTextParam("priority = ?", *patch.Priority)
+		}
+		if patch.DueAt != nil {
+			qrySet.AddTextParam("due_at = ?", nullTime(*patch.DueAt))
+		}
+		if patch.StartAt != nil {
+			qrySet.AddTextParam("start_at = ?", nullTime(*patch.StartAt))
+		}
+		now := time.Now().UTC()
+		if patch.Done != nil {
+			qrySet.AddTextParams(setCompletedAt, *patch.Done, now, now)
+			qrySet.AddTextParam("done = ?", *patch.Done)
+		}
+		if qrySet.SQL() == "" {
+			return 0, service.ErrEmptyPatch
+		}
+		qrySet.AddTextParam("updated_at = ?", now)
+		qryIDs, err := matchingIDs(filter, all)
+		if err != nil {
+			return 0, err
+		}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddText("UPDATE todo")
+		qry.AddQuery(qrySet)
+		qry.AddQuery(qryIDs)
+		return execAffected(ctx, db, "TodoService.UpdateWhere", qry)
+	} else {
+		return 0, service.ErrNoDBInContext
+	}
+}
+
+// DeleteWhere implements service.TodoService.
+func (TodoService) DeleteWhere(ctx context.Context, filter service.TodoFilter, all bool) (int64, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		qryIDs, err := matchingIDs(filter, all)
+		if err != nil {
+			return 0, err
+		}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddText("DELETE FROM todo")
+		qry.AddQuery(qryIDs)
+		return execAffected(ctx, db, "TodoService.DeleteWhere", qry)
+	} else {
+		return 0, service.ErrNoDBInContext
+	}
+}
+
+// matchingIDs builds the WHERE clause selecting the todos matching filter.
+// The filter may join other tables, so it runs as a subquery on ids.
+func matchingIDs(filter service.TodoFilter, all bool) (service.QueryBuilder, error) {
+	f, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if f.empty() && !all {
+		return nil, service.ErrEmptyFilter
+	}
+	var qry service.QueryBuilder = &QueryBuilder{prefix: "WHERE id IN (", sep: " ", suffix: ")"}
+	qry.AddText("SELECT t.id")
+	qry.AddQuery(f.from())
+	qry.AddQuery(f.where())
+	return qry, nil
+}
+
+func execAffected(ctx context.Context, db *sql.DB, name string, qry service.QueryBuilder) (int64, error) {
+	qSql := qry.SQL()
+	qParams := qry.Params()
+	slog.Debug(name, "qry", qSql, "params", qParams)
+	res, err := db.ExecContext(ctx, qSql, qParams...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	service "github.com/senomas/gotodo_service"
+	sqlite "github.com/senomas/gotodo_service_sqlite"
+)
+
+const usage = `usage: gotodo [-db file] migrate <command>
+
+commands:
+  up              apply pending migrations
+  down <version>  roll back migrations newer than version
+  status          list applied, pending and modified migrations, failing
+                  when any is pending or modified
+  dry-run         print the statements up would execute
+`
+
+func main() {
+	dbPath := flag.String("db", envOr("DB_PATH", "gotodo.db"), "sqlite database file")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 || args[0] != "migrate" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open(sqlite.DriverName, *dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx := sqlite.NewContext(context.WithValue(context.Background(), service.ServiceContextDB, db))
+	todoService := ctx.Value(service.TodoServiceContext).(service.TodoService)
+	if err := migrate(ctx, todoService, args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func migrate(ctx context.Context, todoService service.TodoService, args []string) error {
+	switch args[0] {
+	case "up":
+		return todoService.Migrate(ctx)
+	case "down":
+		if len(args) != 2 {
+			return fmt.Errorf("migrate down requires a target version")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %v", args[1], err)
+		}
+		return todoService.Rollback(ctx, version)
+	case "status":
+		entries, err := todoService.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tFILE\tSTATE\tAPPLIED")
+		modified, pending := false, 0
+		for _, e := range entries {
+			applied := ""
+			if e.State != service.MigrationPending {
+				applied = e.Applied.Timestamp.Format("2006-01-02 15:04:05")
+			} else {
+				pending++
+			}
+			if e.State == service.MigrationModified {
+				modified = true
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", e.Version, e.Filename, e.State, applied)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if modified {
+			return service.ErrMigrationModified
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d migrations pending", pending)
+		}
+		return nil
+	case "dry-run":
+		return todoService.MigrateDryRun(ctx, os.Stdout)
+	}
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
+
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,21 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the database/sql driver that opens databases with foreign
+// keys enforced on every connection, as the _foreign_keys=1 DSN parameter of
+// the plain sqlite3 driver does. Deletes cascade with either driver.
+const DriverName = "sqlite3_gotodo"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec("PRAGMA foreign_keys = ON", nil)
+			return err
+		},
+	})
+}
@@ -1,55 +1,251 @@
 package sqlite
 
-import service "github.com/senomas/gotodo_service"
+import (
+	"time"
 
+	service "github.com/senomas/gotodo_service"
+)
+
+// TodoFilter is a service.FilterSpec compiled to SQL: applying a spec to it
+// collects the conditions created through its field filters and groups.
+// Every condition is ANDed at the top level until it is passed to And, Or or
+// Not, which move it into their group.
 type TodoFilter struct {
-	categoryID  FilterInt
-	done        FilterBool
-	title       FilterString
-	description FilterString
-	category    FilterString
+	// search is the FTS5 query of the first Search, used for rank and
+	// snippets.
+	search string
+	conds  []generator
+}
+
+// generator is a service.Filter created by TodoFilter.
+type generator interface {
+	Generate(query service.QueryBuilder)
+}
+
+// compileFilter compiles the spec of filter; a nil filter matches every
+// todo.
+func compileFilter(filter service.TodoFilter) (*TodoFilter, error) {
+	f := &TodoFilter{}
+	if filter == nil {
+		return f, nil
+	}
+	if _, err := filter.Spec().Apply(f); err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
-// Generate implements service.TodoFilter.
+// Generate adds the conditions of f to qryWhere.
 func (f *TodoFilter) Generate(qryWhere service.QueryBuilder) {
-	f.title.Generate(qryWhere)
-	f.description.Generate(qryWhere)
-	f.category.Generate(qryWhere)
-	f.categoryID.Generate(qryWhere)
-	f.done.Generate(qryWhere)
+	for _, c := range f.conds {
+		c.Generate(qryWhere)
+	}
+}
+
+// empty reports whether f has no condition to match. Groups holding no
+// condition, as made by And() or Search(""), render nothing and count as
+// empty.
+func (f *TodoFilter) empty() bool {
+	for _, c := range f.conds {
+		if !emptyGenerator(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func emptyGenerator(g generator) bool {
+	group, ok := g.(*filterGroup)
+	if !ok {
+		return false
+	}
+	for _, c := range group.filters {
+		if !emptyGenerator(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// where generates the WHERE clause of f.
+func (f *TodoFilter) where() service.QueryBuilder {
+	var qryWhere service.QueryBuilder = &QueryBuilder{prefix: "WHERE ", sep: " AND "}
+	f.Generate(qryWhere)
+	return qryWhere
+}
+
+// from generates the FROM clause for f, joining the FTS5 results of its
+// first Search for rank and snippets.
+func (f *TodoFilter) from() service.QueryBuilder {
+	var qryFrom service.QueryBuilder = &QueryBuilder{sep: " "}
+	qryFrom.AddText("FROM todo t JOIN todo_category category ON t.category_id = category.id")
+	if f.search != "" {
+		qryFrom.AddTextParam(`LEFT JOIN (
+      SELECT rowid, bm25(todo_fts) AS rank, snippet(todo_fts, -1, '[', ']', '...', 12) AS snippet
+      FROM todo_fts WHERE todo_fts MATCH ?
+    ) fts ON fts.rowid = t.id`, f.search)
+	}
+	return qryFrom
+}
+
+// columns lists the columns scanTodos reads.
+func (f *TodoFilter) columns() string {
+	if f.search != "" {
+		return todoColumns + ", fts.snippet"
+	}
+	return todoColumns + ", NULL"
 }
 
-// Category implements service.TodoFilter.
+// Category implements service.FilterBuilder.
 func (f *TodoFilter) Category() service.FilterString {
-	f.category.field = "category.name"
-	return &f.category
+	return &FilterString{filter: f, field: "category.name"}
 }
 
-// CategoryID implements service.TodoFilter.
+// CategoryID implements service.FilterBuilder.
 func (f *TodoFilter) CategoryID() service.FilterInt {
-	f.categoryID.field = "category.id"
-	return &f.categoryID
+	return &FilterInt{filter: f, field: "category.id"}
 }
 
-// Description implements service.TodoFilter.
+// Description implements service.FilterBuilder.
 func (f *TodoFilter) Description() service.FilterString {
-	f.description.field = "description"
-	return &f.description
+	return &FilterString{filter: f, field: "description", nullable: true}
 }
 
-// Done implements service.TodoFilter.
+// Done implements service.FilterBuilder.
 func (f *TodoFilter) Done() service.FilterBool {
-	f.done.field = "done"
-	return &f.done
+	return &FilterBool{filter: f, field: "done"}
+}
+
+// Priority implements service.FilterBuilder.
+func (f *TodoFilter) Priority() service.FilterInt {
+	return &FilterInt{filter: f, field: "t.priority"}
+}
+
+// Tags implements service.FilterBuilder.
+func (f *TodoFilter) Tags() service.FilterTags {
+	return &FilterTags{filter: f}
+}
+
+// DueAt implements service.FilterBuilder.
+func (f *TodoFilter) DueAt() service.FilterTime {
+	return &FilterTime{filter: f, field: "t.due_at"}
+}
+
+// StartAt implements service.FilterBuilder.
+func (f *TodoFilter) StartAt() service.FilterTime {
+	return &FilterTime{filter: f, field: "t.start_at"}
+}
+
+// CreatedAt implements service.FilterBuilder.
+func (f *TodoFilter) CreatedAt() service.FilterTime {
+	return &FilterTime{filter: f, field: "t.created_at"}
+}
+
+// UpdatedAt implements service.FilterBuilder.
+func (f *TodoFilter) UpdatedAt() service.FilterTime {
+	return &FilterTime{filter: f, field: "t.updated_at"}
+}
+
+// CompletedAt implements service.FilterBuilder.
+func (f *TodoFilter) CompletedAt() service.FilterTime {
+	return &FilterTime{filter: f, field: "t.completed_at"}
 }
 
-// Title implements service.TodoFilter.
+// Overdue implements service.FilterBuilder. Todos without a due date are
+// not overdue, so that Not(Overdue()) matches them too.
+func (f *TodoFilter) Overdue() service.Filter {
+	return f.cond("IFNULL(t.due_at < ? AND NOT t.done, 0)", time.Now().UTC())
+}
+
+// TopLevel implements service.FilterBuilder.
+func (f *TodoFilter) TopLevel() service.Filter {
+	return f.cond("t.parent_id IS NULL")
+}
+
+// ID implements service.FilterBuilder.
+func (f *TodoFilter) ID() service.FilterInt {
+	return &FilterInt{filter: f, field: "t.id"}
+}
+
+// Title implements service.FilterBuilder.
 func (f *TodoFilter) Title() service.FilterString {
-	f.title.field = "title"
-	return &f.title
+	return &FilterString{filter: f, field: "title"}
+}
+
+// And implements service.FilterBuilder.
+func (f *TodoFilter) And(filters ...service.Filter) service.Filter {
+	return f.group("(", " AND ", ")", filters)
+}
+
+// Or implements service.FilterBuilder.
+func (f *TodoFilter) Or(filters ...service.Filter) service.Filter {
+	return f.group("(", " OR ", ")", filters)
+}
+
+// Not implements service.FilterBuilder. A condition that is NULL, such as
+// one on a NULL description, counts as false, so Not matches its todo.
+func (f *TodoFilter) Not(filter service.Filter) service.Filter {
+	return f.group("NOT IFNULL((", " AND ", "), 0)", []service.Filter{filter})
+}
+
+func (f *TodoFilter) group(prefix, sep, suffix string, filters []service.Filter) service.Filter {
+	group := &filterGroup{prefix: prefix, sep: sep, suffix: suffix}
+	for _, filter := range filters {
+		if g, ok := filter.(generator); ok {
+			f.remove(g)
+			group.filters = append(group.filters, g)
+		}
+	}
+	return f.add(group)
+}
+
+func (f *TodoFilter) cond(text string, params ...any) service.Filter {
+	return f.add(&condition{text: text, params: params})
+}
+
+func (f *TodoFilter) add(filter generator) service.Filter {
+	f.conds = append(f.conds, filter)
+	return filter
+}
+
+func (f *TodoFilter) remove(filter generator) {
+	for i, c := range f.conds {
+		if c == filter {
+			f.conds = append(f.conds[:i], f.conds[i+1:]...)
+			return
+		}
+	}
+}
+
+// condition is a single field comparison.
+type condition struct {
+	text   string
+	params []any
+}
+
+// Generate implements generator.
+func (c *condition) Generate(query service.QueryBuilder) {
+	query.AddTextParams(c.text, c.params...)
+}
+
+// filterGroup renders its filters joined by sep between prefix and suffix.
+type filterGroup struct {
+	prefix  string
+	sep     string
+	suffix  string
+	filters []generator
+}
+
+// Generate implements generator.
+func (g *filterGroup) Generate(query service.QueryBuilder) {
+	qry := &QueryBuilder{prefix: g.prefix, sep: g.sep, suffix: g.suffix}
+	for _, filter := range g.filters {
+		filter.Generate(qry)
+	}
+	query.AddQuery(qry)
 }
 
 // Filter implements service.TodoService.
 func (TodoService) Filter() service.TodoFilter {
-	return &TodoFilter{}
+	return service.NewTodoFilter()
 }
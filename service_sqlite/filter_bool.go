@@ -3,16 +3,11 @@ package sqlite
 import service "github.com/senomas/gotodo_service"
 
 type FilterBool struct {
-	field string
-	query QueryBuilder
-}
-
-// generate implements service.Filter.
-func (f *FilterBool) Generate(query service.QueryBuilder) {
-	query.AddQuery(&f.query)
+	filter *TodoFilter
+	field  string
 }
 
 // Equal implements service.FilterBool.
-func (*FilterBool) Equal(bool) service.Filter {
-	panic("unimplemented")
+func (f *FilterBool) Equal(v bool) service.Filter {
+	return f.filter.cond(f.field+" = ?", v)
 }
@@ -3,47 +3,42 @@ package sqlite
 import service "github.com/senomas/gotodo_service"
 
 type FilterInt struct {
-	field string
-	query QueryBuilder
+	filter *TodoFilter
+	field  string
 }
 
-// generate implements service.Filter.
-func (f *FilterInt) Generate(query service.QueryBuilder) {
-	query.AddQuery(&f.query)
-}
-
-// Between implements service.FilterInt.
+// Between implements service.FilterInt. Both bounds are inclusive, as in SQL
+// BETWEEN.
 func (f *FilterInt) Between(v1 int64, v2 int64) service.Filter {
-	f.query.AddTextParams(f.field+" > ? AND "+f.field+" < ?", v1, v2)
-	return f
+	return f.filter.cond(f.field+" BETWEEN ? AND ?", v1, v2)
 }
 
 // Equal implements service.FilterInt.
-func (*FilterInt) Equal(int64) service.Filter {
-	panic("unimplemented")
+func (f *FilterInt) Equal(v int64) service.Filter {
+	return f.filter.cond(f.field+" = ?", v)
 }
 
 // Greater implements service.FilterInt.
-func (*FilterInt) Greater(int64) service.Filter {
-	panic("unimplemented")
+func (f *FilterInt) Greater(v int64) service.Filter {
+	return f.filter.cond(f.field+" > ?", v)
 }
 
 // GreaterOrEqual implements service.FilterInt.
-func (*FilterInt) GreaterOrEqual(int64) service.Filter {
-	panic("unimplemented")
+func (f *FilterInt) GreaterOrEqual(v int64) service.Filter {
+	return f.filter.cond(f.field+" >= ?", v)
 }
 
 // Less implements service.FilterInt.
-func (*FilterInt) Less(int64) service.Filter {
-	panic("unimplemented")
+func (f *FilterInt) Less(v int64) service.Filter {
+	return f.filter.cond(f.field+" < ?", v)
 }
 
 // LessOrEqual implements service.FilterInt.
-func (*FilterInt) LessOrEqual(int64) service.Filter {
-	panic("unimplemented")
+func (f *FilterInt) LessOrEqual(v int64) service.Filter {
+	return f.filter.cond(f.field+" <= ?", v)
 }
 
 // NotEqual implements service.FilterInt.
-func (*FilterInt) NotEqual(int64) service.Filter {
-	panic("unimplemented")
+func (f *FilterInt) NotEqual(v int64) service.Filter {
+	return f.filter.cond(f.field+" <> ?", v)
 }
@@ -3,35 +3,58 @@ package sqlite
 import service "github.com/senomas/gotodo_service"
 
 type FilterString struct {
-	field string
-	query QueryBuilder
-}
-
-// generate implements service.Filter.
-func (f *FilterString) Generate(query service.QueryBuilder) {
-	query.AddQuery(&f.query)
+	filter *TodoFilter
+	field  string
+	// nullable fields also match NULL on NotEqual and NotLike, the way a Go
+	// caller comparing sql.NullString.String would expect.
+	nullable bool
 }
 
 // Equal implements service.FilterString.
 func (f *FilterString) Equal(v string) service.Filter {
-	f.query.AddTextParams(f.field+" = ?", v)
-	return f
+	return f.filter.cond(f.field+" = ?", v)
 }
 
 // Like implements service.FilterString.
 func (f *FilterString) Like(v string) service.Filter {
-	f.query.AddTextParams(f.field+" like ?", v)
-	return f
+	return f.filter.cond(f.field+" like ?", v)
 }
 
 // NotEqual implements service.FilterString.
-func (f *FilterString) NotEqual(string) service.Filter {
-	panic("unimplemented")
+func (f *FilterString) NotEqual(v string) service.Filter {
+	return f.filter.cond(f.orNull(f.field+" <> ?"), v)
 }
 
 // NotLike implements service.FilterString.
-func (f *FilterString) NotLike(string) service.Filter {
-	panic("unimplemented")
+func (f *FilterString) NotLike(v string) service.Filter {
+	return f.filter.cond(f.orNull(f.field+" not like ?"), v)
+}
+
+// Contains implements service.FilterString.
+func (f *FilterString) Contains(v string) service.Filter {
+	return f.filter.cond(f.field+` like ? ESCAPE '\'`, "%"+likeEscaper.Replace(v)+"%")
+}
+
+// NotContains implements service.FilterString.
+func (f *FilterString) NotContains(v string) service.Filter {
+	return f.filter.cond(f.orNull(f.field+` not like ? ESCAPE '\'`), "%"+likeEscaper.Replace(v)+"%")
+}
+
+// IsNull implements service.FilterString.
+func (f *FilterString) IsNull() service.Filter {
+	return f.filter.cond(f.field + " IS NULL")
+}
+
+// IsNotNull implements service.FilterString.
+func (f *FilterString) IsNotNull() service.Filter {
+	return f.filter.cond(f.field + " IS NOT NULL")
+}
+
+func (f *FilterString) orNull(cond string) string {
+	if f.nullable {
+		return "(" + cond + " OR " + f.field + " IS NULL)"
+	}
+	return cond
 }
 
 // In implements service.FilterString.
@@ -46,6 +69,5 @@ func (f *FilterString) In(v []string) service.Filter {
 		params[i] = s
 	}
 	str += ")"
-	f.query.AddTextParams(str, params...)
-	return f
+	return f.filter.cond(str, params...)
 }
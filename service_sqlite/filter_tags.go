@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"slices"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+type FilterTags struct {
+	filter *TodoFilter
+}
+
+// HasAny implements service.FilterTags.
+func (f *FilterTags) HasAny(names []string) service.Filter {
+	if len(names) == 0 {
+		return f.filter.cond("0 = 1")
+	}
+	qry := taggedWith(names)
+	return f.filter.cond("t.id IN ("+qry.SQL()+")", qry.Params()...)
+}
+
+// HasAll implements service.FilterTags.
+func (f *FilterTags) HasAll(names []string) service.Filter {
+	names = distinct(names)
+	if len(names) == 0 {
+		// no tags to require adds no condition
+		return f.filter.And()
+	}
+	qry := taggedWith(names)
+	qry.AddTextParam("GROUP BY tt.todo_id HAVING COUNT(tt.tag_id) = ?", len(names))
+	return f.filter.cond("t.id IN ("+qry.SQL()+")", qry.Params()...)
+}
+
+// HasNone implements service.FilterTags.
+func (f *FilterTags) HasNone(names []string) service.Filter {
+	if len(names) == 0 {
+		// no tags to exclude adds no condition
+		return f.filter.And()
+	}
+	qry := taggedWith(names)
+	return f.filter.cond("t.id NOT IN ("+qry.SQL()+")", qry.Params()...)
+}
+
+// taggedWith selects the ids of the todos tagged with any of names.
+func taggedWith(names []string) service.QueryBuilder {
+	var qryIn service.QueryBuilder = &QueryBuilder{prefix: "WHERE tag.name IN (", sep: ", ", suffix: ")"}
+	for _, name := range names {
+		qryIn.AddTextParam("?", name)
+	}
+	var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+	qry.AddText("SELECT tt.todo_id FROM todo_tag tt JOIN tag ON tag.id = tt.tag_id")
+	qry.AddQuery(qryIn)
+	return qry
+}
+
+func distinct(names []string) []string {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// FilterTime compares a DATETIME column. Times are stored in UTC, where the
+// driver's text format sorts in time order.
+type FilterTime struct {
+	filter *TodoFilter
+	field  string
+}
+
+// After implements service.FilterTime.
+func (f *FilterTime) After(v time.Time) service.Filter {
+	return f.filter.cond(f.field+" > ?", v.UTC())
+}
+
+// Before implements service.FilterTime.
+func (f *FilterTime) Before(v time.Time) service.Filter {
+	return f.filter.cond(f.field+" < ?", v.UTC())
+}
+
+// Between implements service.FilterTime.
+func (f *FilterTime) Between(v1 time.Time, v2 time.Time) service.Filter {
+	return f.filter.cond(f.field+" BETWEEN ? AND ?", v1.UTC(), v2.UTC())
+}
+
+// IsNotNull implements service.FilterTime.
+func (f *FilterTime) IsNotNull() service.Filter {
+	return f.filter.cond(f.field + " IS NOT NULL")
+}
+
+// IsNull implements service.FilterTime.
+func (f *FilterTime) IsNull() service.Filter {
+	return f.filter.cond(f.field + " IS NULL")
+}
+
+// Within implements service.FilterTime.
+func (f *FilterTime) Within(d time.Duration) service.Filter {
+	now := time.Now()
+	if d < 0 {
+		return f.Between(now.Add(d), now)
+	}
+	return f.Between(now, now.Add(d))
+}
+
+// nullTime is t as stored, in UTC, or NULL.
+func nullTime(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC()
+}
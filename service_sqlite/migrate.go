@@ -3,52 +3,48 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
-	"runtime"
+	"slices"
 	"strings"
 
 	service "github.com/senomas/gotodo_service"
 )
 
+//go:embed migration/*.sql
+var migrations embed.FS
+
 // Migrate implements service.TodoService.
-func (TodoService) Migrate(ctx context.Context) error {
+func (s TodoService) Migrate(ctx context.Context) error {
 	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
-		if path, ok := os.LookupEnv("MIGRATION_PATH"); ok {
-			qry := `
-        CREATE TABLE IF NOT EXISTS _migration (
-          id        INTEGER PRIMARY KEY AUTOINCREMENT,
-          filename  TEXT,
-          hash      TEXT,
-          success   BOOLEAN,
-          result    TEXT,
-          timestamp DATETIME
-        )
-      `
-			_, err := db.ExecContext(ctx, qry)
+		if err := checkFTS(ctx, db); err != nil {
+			return err
+		}
+		fsys, err := s.migrationFS()
+		if err != nil {
+			return err
+		}
+		return s.withMigrationLock(ctx, db, func(ctx context.Context, owner string) error {
+			err := migrationTable(ctx, db)
 			if err != nil {
-				slog.Warn("sql error", "qry", qry, "error", err)
 				return err
 			}
-			if path == "" {
-				_, filename, _, _ := runtime.Caller(0)
-				path = filepath.Join(filepath.Dir(filename), "migration")
-			} else if !strings.HasPrefix(path, "/") {
-				ex, err := os.Executable()
-				if err != nil {
-					return err
+			return service.Migrate(ctx, fsys, migrationGetter(db), func(ctx context.Context, tx *sql.Tx, m service.Migration) error {
+				if tx != nil {
+					if err := refreshMigrationLock(ctx, tx, owner); err != nil {
+						return err
+					}
 				}
-				path = filepath.Join(ex, path)
-			} else {
-				path = filepath.Clean(path)
-			}
-			err = service.Migrate(ctx, path, func(ctx context.Context, m service.Migration) error {
-				qry = `
+				qry := `
           INSERT INTO _migration (filename, hash, success, result, timestamp)
           VALUES ($1, $2, $3, $4, $5)
         `
-				rs, err := db.ExecContext(ctx, qry, m.Filename, m.Hash, m.Success, m.Result, m.Timestamp)
+				rs, err := migrationExecer(db, tx).ExecContext(ctx, qry, m.Filename, m.Hash, m.Success, m.Result, m.Timestamp)
 				if err != nil {
 					return err
 				}
@@ -57,46 +53,245 @@ func (TodoService) Migrate(ctx context.Context) error {
 					return err
 				}
 				return nil
-			}, func(ctx context.Context, qry string) error {
-				_, err := db.ExecContext(ctx, qry)
-				return err
-			})
+			}, migrationTx(db), nil)
+		})
+	} else {
+		return service.ErrNoDBInContext
+	}
+}
+
+// Rollback implements service.TodoService.
+func (s TodoService) Rollback(ctx context.Context, version int64) error {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		fsys, err := s.migrationFS()
+		if err != nil {
+			return err
+		}
+		return s.withMigrationLock(ctx, db, func(ctx context.Context, owner string) error {
+			err := migrationTable(ctx, db)
 			if err != nil {
 				return err
 			}
-		} else {
-			_, err := db.Exec(`
-        PRAGMA foreign_keys = ON;
-        PRAGMA integrity_check;
-      `)
-			if err != nil {
+			return service.Rollback(ctx, fsys, version, migrationGetter(db), func(ctx context.Context, tx *sql.Tx, m service.Migration) error {
+				if tx != nil {
+					if err := refreshMigrationLock(ctx, tx, owner); err != nil {
+						return err
+					}
+				}
+				_, err := migrationExecer(db, tx).ExecContext(ctx, `
+          UPDATE _migration SET rolled_back = TRUE, result = result || $1 WHERE id = $2
+        `, "\nROLLBACK "+m.Timestamp.String()+"\n"+m.Result, m.ID)
 				return err
-			}
+			}, migrationTx(db), nil)
+		})
+	} else {
+		return service.ErrNoDBInContext
+	}
+}
 
-			_, err = db.Exec(`
-      CREATE TABLE IF NOT EXISTS todo_category (
-          id INTEGER PRIMARY KEY,
-          name TEXT NOT NULL
-        )
-      `)
-			if err != nil {
-				return err
-			}
+// MigrationStatus implements service.TodoService.
+func (s TodoService) MigrationStatus(ctx context.Context) ([]service.MigrationEntry, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		getMigrate, err := migrationReader(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		fsys, err := s.migrationFS()
+		if err != nil {
+			return nil, err
+		}
+		return service.MigrationStatus(ctx, fsys, getMigrate)
+	} else {
+		return nil, service.ErrNoDBInContext
+	}
+}
 
-			_, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS todo (
-          id INTEGER PRIMARY KEY,
-          title TEXT NOT NULL,
-          description TEXT,
-          category_id INTEGER NOT NULL,
-          done BOOLEAN NOT NULL DEFAULT FALSE,
-          FOREIGN KEY (category_id) REFERENCES todo_category (id)
-        )
-      `)
+// MigrateDryRun implements service.TodoService.
+func (s TodoService) MigrateDryRun(ctx context.Context, w io.Writer) error {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		getMigrate, err := migrationReader(ctx, db)
+		if err != nil {
 			return err
 		}
+		fsys, err := s.migrationFS()
+		if err != nil {
+			return err
+		}
+		return service.MigrateDryRun(ctx, fsys, getMigrate, w)
 	} else {
 		return service.ErrNoDBInContext
 	}
+}
+
+// migrationFS selects the migration files: MigrationFS when set, then the
+// MIGRATION_PATH directory, resolved against the executable's directory when
+// relative, and finally the migrations embedded in the binary.
+func (s TodoService) migrationFS() (fs.FS, error) {
+	if s.MigrationFS != nil {
+		return s.MigrationFS, nil
+	}
+	if path := os.Getenv("MIGRATION_PATH"); path != "" {
+		if !filepath.IsAbs(path) {
+			ex, err := os.Executable()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(filepath.Dir(ex), path)
+		}
+		return os.DirFS(filepath.Clean(path)), nil
+	}
+	base, err := fs.Sub(migrations, "migration")
+	if err != nil {
+		return nil, err
+	}
+	fts, err := fs.Sub(ftsMigrations, "migration_fts5")
+	if err != nil {
+		return nil, err
+	}
+	return unionFS{base, fts}, nil
+}
+
+// unionFS overlays flat migration directories; the first holding a name
+// wins and missing directories are empty.
+type unionFS []fs.FS
+
+func (u unionFS) Open(name string) (fs.File, error) {
+	for _, fsys := range u {
+		f, err := fsys.Open(name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (u unionFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	seen := map[string]bool{}
+	for _, fsys := range u {
+		list, err := fs.ReadDir(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, e := range list {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// migrationTable creates the _migration bookkeeping table, upgrading tables
+// created before rollback support.
+func migrationTable(ctx context.Context, db *sql.DB) error {
+	qry := `
+    CREATE TABLE IF NOT EXISTS _migration (
+      id          INTEGER PRIMARY KEY AUTOINCREMENT,
+      filename    TEXT,
+      hash        TEXT,
+      success     BOOLEAN,
+      result      TEXT,
+      timestamp   DATETIME,
+      rolled_back BOOLEAN NOT NULL DEFAULT FALSE
+    )
+  `
+	_, err := db.ExecContext(ctx, qry)
+	if err != nil {
+		slog.Warn("sql error", "qry", qry, "error", err)
+		return err
+	}
+	var count int
+	err = db.QueryRowContext(ctx, `
+    SELECT COUNT(*) FROM pragma_table_info('_migration') WHERE name = 'rolled_back'
+  `).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		qry = "ALTER TABLE _migration ADD COLUMN rolled_back BOOLEAN NOT NULL DEFAULT FALSE"
+		_, err = db.ExecContext(ctx, qry)
+		if err != nil {
+			slog.Warn("sql error", "qry", qry, "error", err)
+			return err
+		}
+	}
 	return nil
 }
+
+// migrationReader is migrationGetter for MigrationStatus and MigrateDryRun,
+// which must not write: without a _migration table every migration is
+// pending, and a table created before rollback support has none rolled back.
+func migrationReader(ctx context.Context, db *sql.DB) (func(context.Context, string) (service.Migration, error), error) {
+	var table, rolledBack bool
+	err := db.QueryRowContext(ctx, `
+    SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = '_migration'),
+      EXISTS (SELECT 1 FROM pragma_table_info('_migration') WHERE name = 'rolled_back')
+  `).Scan(&table, &rolledBack)
+	if err != nil {
+		return nil, err
+	}
+	if !table {
+		return func(context.Context, string) (service.Migration, error) {
+			return service.Migration{}, service.ErrNoData
+		}, nil
+	}
+	if !rolledBack {
+		return queryMigration(db, "FALSE"), nil
+	}
+	return migrationGetter(db), nil
+}
+
+func migrationGetter(db *sql.DB) func(context.Context, string) (service.Migration, error) {
+	return queryMigration(db, "rolled_back")
+}
+
+// queryMigration reads the last applied migration of a file, with rolledBack
+// the expression of its rolled_back column.
+func queryMigration(db *sql.DB, rolledBack string) func(context.Context, string) (service.Migration, error) {
+	return func(ctx context.Context, filename string) (service.Migration, error) {
+		var m service.Migration
+		rows, err := db.QueryContext(ctx, `
+      SELECT id, filename, hash, success, result, timestamp, `+rolledBack+`
+      FROM _migration
+      WHERE filename = $1 AND success AND NOT `+rolledBack+`
+      ORDER BY id DESC LIMIT 1
+    `, filename)
+		if err != nil {
+			return m, err
+		}
+		defer rows.Close()
+		if rows.Next() {
+			err = rows.Scan(&m.ID, &m.Filename, &m.Hash, &m.Success, &m.Result, &m.Timestamp, &m.RolledBack)
+			if err != nil {
+				return m, err
+			}
+			m.Version, err = service.MigrationVersion(m.Filename)
+			return m, err
+		}
+		return m, service.ErrNoData
+	}
+}
+
+// migrationExecer runs the statements recording a migration in tx, or in db
+// when there is none.
+func migrationExecer(db *sql.DB, tx *sql.Tx) interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+} {
+	if tx != nil {
+		return tx
+	}
+	return db
+}
+
+func migrationTx(db *sql.DB) func(context.Context) (*sql.Tx, error) {
+	return func(ctx context.Context) (*sql.Tx, error) {
+		return db.BeginTx(ctx, nil)
+	}
+}
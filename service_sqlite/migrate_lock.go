@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	service "github.com/senomas/gotodo_service"
+)
+
+const (
+	// DefaultMigrationLockTimeout is used when TodoService.MigrationLockTimeout
+	// is zero.
+	DefaultMigrationLockTimeout = 30 * time.Second
+
+	// migrationLockTTL is how long a lock row is honoured; a process that
+	// died while migrating must not block every later start. Every migrated
+	// file renews it, see refreshMigrationLock.
+	migrationLockTTL = 10 * time.Minute
+)
+
+// withMigrationLock runs fn while holding the _migration_lock row, claimed
+// as owner.
+func (s TodoService) withMigrationLock(
+	ctx context.Context, db *sql.DB, fn func(ctx context.Context, owner string) error,
+) error {
+	_, err := db.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS _migration_lock (
+      id        INTEGER PRIMARY KEY CHECK (id = 1),
+      owner     TEXT NOT NULL,
+      locked_at DATETIME NOT NULL
+    )
+  `)
+	if err != nil {
+		return err
+	}
+	timeout := s.MigrationLockTimeout
+	if timeout == 0 {
+		timeout = DefaultMigrationLockTimeout
+	}
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+	return service.WithMigrationLock(ctx, timeout, func(ctx context.Context) (bool, error) {
+		return tryMigrationLock(ctx, db, owner)
+	}, func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, "DELETE FROM _migration_lock WHERE id = 1 AND owner = $1", owner)
+		return err
+	}, func(ctx context.Context) error {
+		return fn(ctx, owner)
+	})
+}
+
+// refreshMigrationLock renews the lock of owner in the transaction of a
+// migrated file, so a long run is not taken over as stale between files. It
+// fails with service.ErrMigrationLocked once another process took the lock,
+// rolling the file back.
+func refreshMigrationLock(ctx context.Context, tx *sql.Tx, owner string) error {
+	res, err := tx.ExecContext(ctx, "UPDATE _migration_lock SET locked_at = $1 WHERE id = 1 AND owner = $2", time.Now(), owner)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: lock taken over by another process", service.ErrMigrationLocked)
+	}
+	return nil
+}
+
+// tryMigrationLock claims the lock row inside BEGIN IMMEDIATE, so only one
+// connection can check and claim it at a time.
+func tryMigrationLock(ctx context.Context, db *sql.DB, owner string) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	if err != nil {
+		if isBusy(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		}
+	}()
+	var holder string
+	var lockedAt time.Time
+	err = conn.QueryRowContext(ctx, "SELECT owner, locked_at FROM _migration_lock WHERE id = 1").Scan(&holder, &lockedAt)
+	if err == nil {
+		if time.Since(lockedAt) < migrationLockTTL {
+			slog.Debug("TodoService migration locked", "owner", holder, "lockedAt", lockedAt)
+			return false, nil
+		}
+		slog.Warn("TodoService taking over stale migration lock", "owner", holder, "lockedAt", lockedAt)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		if isBusy(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, err = conn.ExecContext(ctx, `
+    INSERT OR REPLACE INTO _migration_lock (id, owner, locked_at) VALUES (1, $1, $2)
+  `, owner, time.Now())
+	if err != nil {
+		return false, err
+	}
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		if isBusy(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	committed = true
+	return true, nil
+}
+
+func isBusy(err error) bool {
+	var serr sqlite3.Error
+	if errors.As(err, &serr) {
+		return serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todo_start_at;
+DROP INDEX IF EXISTS todo_due_at;
+
+ALTER TABLE todo DROP COLUMN start_at;
+ALTER TABLE todo DROP COLUMN due_at;
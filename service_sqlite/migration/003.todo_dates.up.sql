@@ -0,0 +1,5 @@
+ALTER TABLE todo ADD COLUMN due_at DATETIME;
+ALTER TABLE todo ADD COLUMN start_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS todo_due_at ON todo (due_at);
+CREATE INDEX IF NOT EXISTS todo_start_at ON todo (start_at);
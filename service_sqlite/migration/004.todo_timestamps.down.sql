@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todo_completed_at;
+
+ALTER TABLE todo DROP COLUMN completed_at;
+ALTER TABLE todo DROP COLUMN updated_at;
+ALTER TABLE todo DROP COLUMN created_at;
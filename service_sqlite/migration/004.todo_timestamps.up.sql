@@ -0,0 +1,12 @@
+-- existing todos get the time of the migration, times are stored in UTC in
+-- the driver's format so they compare as text
+ALTER TABLE todo ADD COLUMN created_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE todo ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE todo ADD COLUMN completed_at DATETIME;
+
+UPDATE todo SET
+  created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'),
+  updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'),
+  completed_at = CASE WHEN done THEN strftime('%Y-%m-%d %H:%M:%S+00:00', 'now') END;
+
+CREATE INDEX IF NOT EXISTS todo_completed_at ON todo (completed_at);
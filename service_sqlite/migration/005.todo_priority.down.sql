@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS todo_priority;
+
+ALTER TABLE todo DROP COLUMN priority;
@@ -0,0 +1,3 @@
+ALTER TABLE todo ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS todo_priority ON todo (priority);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS todo_tag_tag_delete;
+DROP TRIGGER IF EXISTS todo_tag_todo_delete;
+DROP TABLE IF EXISTS todo_tag;
+DROP TABLE IF EXISTS tag;
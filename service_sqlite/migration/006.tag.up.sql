@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS tag (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS todo_tag (
+  todo_id INTEGER NOT NULL,
+  tag_id INTEGER NOT NULL,
+  PRIMARY KEY (todo_id, tag_id),
+  FOREIGN KEY (todo_id) REFERENCES todo (id) ON DELETE CASCADE,
+  FOREIGN KEY (tag_id) REFERENCES tag (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS todo_tag_tag_id ON todo_tag (tag_id);
+
+-- foreign_keys is off on most connections, so links go with their todo or
+-- tag here
+CREATE TRIGGER IF NOT EXISTS todo_tag_todo_delete AFTER DELETE ON todo BEGIN
+  DELETE FROM todo_tag WHERE todo_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS todo_tag_tag_delete AFTER DELETE ON tag BEGIN
+  DELETE FROM todo_tag WHERE tag_id = old.id;
+END;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS todo_parent_delete;
+DROP INDEX IF EXISTS todo_parent_id;
+
+ALTER TABLE todo DROP COLUMN parent_id;
@@ -0,0 +1,14 @@
+ALTER TABLE todo ADD COLUMN parent_id INTEGER REFERENCES todo (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS todo_parent_id ON todo (parent_id);
+
+-- foreign_keys is off on most connections and triggers do not recurse, so
+-- the whole subtree of a deleted todo goes here
+CREATE TRIGGER IF NOT EXISTS todo_parent_delete AFTER DELETE ON todo BEGIN
+  DELETE FROM todo WHERE id IN (
+    WITH RECURSIVE sub(id) AS (
+      SELECT id FROM todo WHERE parent_id = old.id
+      UNION SELECT t.id FROM todo t JOIN sub ON t.parent_id = sub.id
+    ) SELECT id FROM sub
+  );
+END;
@@ -0,0 +1,21 @@
+CREATE VIRTUAL TABLE todo_fts USING fts5(
+  title,
+  description,
+  content = 'todo',
+  content_rowid = 'id'
+);
+
+INSERT INTO todo_fts (todo_fts) VALUES ('rebuild');
+
+CREATE TRIGGER todo_fts_insert AFTER INSERT ON todo BEGIN
+  INSERT INTO todo_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+CREATE TRIGGER todo_fts_delete AFTER DELETE ON todo BEGIN
+  INSERT INTO todo_fts (todo_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+
+CREATE TRIGGER todo_fts_update AFTER UPDATE OF title, description ON todo BEGIN
+  INSERT INTO todo_fts (todo_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+  INSERT INTO todo_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
@@ -0,0 +1,33 @@
+//go:build !(sqlite_fts5 || fts5)
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// Without go-sqlite3's sqlite_fts5 tag Search falls back to LIKE on title and
+// description, without ranking or snippets, and SortRank fails with
+// service.ErrUnsupported.
+const ftsEnabled = false
+
+var ftsMigrations embed.FS
+
+// checkFTS refuses a database migrated by a build with FTS5: its sync
+// triggers would fail on every write to todo.
+func checkFTS(ctx context.Context, db *sql.DB) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'todo_fts')").Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: database uses FTS5 search, build with the sqlite_fts5 tag", service.ErrUnsupported)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+var (
+	defaultCursorKey     []byte
+	defaultCursorKeyOnce sync.Once
+)
+
+func (s TodoService) cursorKey() []byte {
+	if s.CursorKey != nil {
+		return s.CursorKey
+	}
+	defaultCursorKeyOnce.Do(func() {
+		defaultCursorKey = make([]byte, 32)
+		if _, err := rand.Read(defaultCursorKey); err != nil {
+			panic(err)
+		}
+	})
+	return defaultCursorKey
+}
+
+// FindPage implements service.TodoService.
+func (s TodoService) FindPage(
+	ctx context.Context, filter service.TodoFilter, sort service.Sort, cursor string, limit int,
+) (service.Page, error) {
+	var page service.Page
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		if limit < 1 {
+			return page, fmt.Errorf("invalid limit %d", limit)
+		}
+		f, err := compileFilter(filter)
+		if err != nil {
+			return page, err
+		}
+		keys, err := sortKeys(sort, f)
+		if err != nil {
+			return page, err
+		}
+		for _, key := range keys {
+			if key.value == nil {
+				return page, fmt.Errorf("%w: %s cannot be paged", service.ErrInvalidSort, key.field)
+			}
+		}
+		qryWhere := f.where()
+		if cursor != "" {
+			values, err := s.parseCursor(keys, cursor)
+			if err != nil {
+				return page, err
+			}
+			qryWhere.AddQuery(keysetAfter(keys, values))
+		}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddText("SELECT " + f.columns())
+		qry.AddQuery(f.from())
+		qry.AddQuery(qryWhere)
+		qry.AddQuery(orderBy(keys))
+		qry.AddTextParam("LIMIT ?", limit+1)
+		qSql := qry.SQL()
+		qParams := qry.Params()
+		slog.Debug("TodoService.FindPage", "qry", qSql, "params", qParams)
+		rows, err := db.QueryContext(ctx, qSql, qParams...)
+		if err != nil {
+			return page, err
+		}
+		defer rows.Close()
+		page.Todos, err = scanTodos(rows)
+		if err != nil {
+			return page, err
+		}
+		if len(page.Todos) > limit {
+			page.Todos = page.Todos[:limit]
+			last := page.Todos[limit-1]
+			c := service.Cursor{Sort: cursorSort(keys), Values: make([]any, len(keys))}
+			for i, key := range keys {
+				c.Values[i] = key.value(last)
+			}
+			page.Next, err = service.SignCursor(s.cursorKey(), c)
+			if err != nil {
+				return page, err
+			}
+		}
+		return page, loadTags(ctx, db, page.Todos)
+	} else {
+		return page, service.ErrNoDBInContext
+	}
+}
+
+// parseCursor verifies cursor and returns its values, which must have been
+// produced for keys.
+func (s TodoService) parseCursor(keys []sortKey, cursor string) ([]any, error) {
+	c, err := service.ParseCursor(s.cursorKey(), cursor)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(c.Sort, cursorSort(keys)) || len(c.Values) != len(keys) {
+		return nil, service.ErrInvalidCursor
+	}
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		values[i], err = key.parse(c.Values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -8,6 +8,7 @@ import (
 
 type QueryBuilder struct {
 	prefix string
+	suffix string
 	sep    string
 	sql    []string
 	params []any
@@ -67,7 +68,7 @@ func (q *QueryBuilder) AddTextParams(text string, params ...any) {
 // SQL implements service.QueryBuilder.
 func (q *QueryBuilder) SQL() string {
 	if len(q.sql) > 0 {
-		return q.prefix + strings.Join(q.sql, q.sep)
+		return q.prefix + strings.Join(q.sql, q.sep) + q.suffix
 	}
 	return ""
 }
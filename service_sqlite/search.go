@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"strings"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// searchTerm is a word, matched as a prefix, or a quoted phrase.
+type searchTerm struct {
+	text   string
+	phrase bool
+}
+
+// parseSearch splits query into words and "quoted phrases"; an unterminated
+// quote runs to the end of query.
+func parseSearch(query string) []searchTerm {
+	var terms []searchTerm
+	for {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			return terms
+		}
+		if query[0] == '"' {
+			phrase, rest, _ := strings.Cut(query[1:], `"`)
+			if phrase = strings.Join(strings.Fields(phrase), " "); phrase != "" {
+				terms = append(terms, searchTerm{text: phrase, phrase: true})
+			}
+			query = rest
+			continue
+		}
+		end := strings.IndexAny(query, " \t\r\n\"")
+		if end < 0 {
+			end = len(query)
+		}
+		if word := strings.TrimRight(query[:end], "*"); word != "" {
+			terms = append(terms, searchTerm{text: word})
+		}
+		query = query[end:]
+	}
+}
+
+// ftsMatch renders terms as an FTS5 query. Every term is quoted, so no user
+// input is read as FTS5 syntax.
+func ftsMatch(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + strings.ReplaceAll(term.text, `"`, `""`) + `"`
+		if !term.phrase {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search implements service.FilterBuilder.
+func (f *TodoFilter) Search(query string) service.Filter {
+	terms := parseSearch(query)
+	if len(terms) == 0 {
+		// nothing to look for adds no condition
+		return f.And()
+	}
+	if ftsEnabled {
+		match := ftsMatch(terms)
+		if f.search == "" {
+			f.search = match
+		}
+		return f.cond("t.id IN (SELECT rowid FROM todo_fts WHERE todo_fts MATCH ?)", match)
+	}
+	conds := make([]service.Filter, len(terms))
+	for i, term := range terms {
+		like := "%" + likeEscaper.Replace(term.text) + "%"
+		conds[i] = f.cond(`(t.title LIKE ? ESCAPE '\' OR IFNULL(t.description, '') LIKE ? ESCAPE '\')`, like, like)
+	}
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return f.And(conds...)
+}
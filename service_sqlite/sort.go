@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// sortColumn maps a service.SortField onto its column and onto the value a
+// todo holds for it, which FindPage stores in its cursors.
+type sortColumn struct {
+	expr  string
+	value func(service.Todo) any
+	parse func(any) (any, error)
+}
+
+// noDueAt sorts after every stored due date.
+const noDueAt = "9999-12-31"
+
+// sortColumns is the whitelist of columns Find may order by.
+var sortColumns = map[service.SortField]sortColumn{
+	service.SortID: {
+		expr:  "t.id",
+		value: func(t service.Todo) any { return t.ID },
+		parse: parseCursorInt,
+	},
+	service.SortTitle: {
+		expr:  "t.title",
+		value: func(t service.Todo) any { return t.Title },
+		parse: parseCursorString,
+	},
+	service.SortCategory: {
+		expr:  "category.name",
+		value: func(t service.Todo) any { return t.Category.Name },
+		parse: parseCursorString,
+	},
+	service.SortDone: {
+		expr:  "t.done",
+		value: func(t service.Todo) any { return t.Done },
+		parse: parseCursorBool,
+	},
+	service.SortCreated: {
+		expr:  "t.created_at",
+		value: func(t service.Todo) any { return t.CreatedAt.UTC() },
+		parse: parseCursorTime,
+	},
+	service.SortUpdated: {
+		expr:  "t.updated_at",
+		value: func(t service.Todo) any { return t.UpdatedAt.UTC() },
+		parse: parseCursorTime,
+	},
+	// keyset comparisons need a value for open todos, '' sorts before any
+	// stored time
+	service.SortCompleted: {
+		expr: "IFNULL(t.completed_at, '')",
+		value: func(t service.Todo) any {
+			if t.CompletedAt.Valid {
+				return t.CompletedAt.Time.UTC()
+			}
+			return ""
+		},
+		parse: parseCursorTimeOr(""),
+	},
+	service.SortPriority: {
+		expr:  "t.priority",
+		value: func(t service.Todo) any { return int64(t.Priority) },
+		parse: parseCursorInt,
+	},
+	service.SortDueAt: {
+		expr: "IFNULL(t.due_at, '" + noDueAt + "')",
+		value: func(t service.Todo) any {
+			if t.DueAt.Valid {
+				return t.DueAt.Time.UTC()
+			}
+			return noDueAt
+		},
+		parse: parseCursorTimeOr(noDueAt),
+	},
+	// now in the stored format, to the millisecond
+	service.SortOverdue: {
+		expr: "IFNULL(t.due_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') AND NOT t.done, 0)",
+		value: func(t service.Todo) any {
+			return t.DueAt.Valid && t.DueAt.Time.Before(time.Now()) && !t.Done
+		},
+		parse: parseCursorBool,
+	},
+	// bm25 is lower for better matches, todos matched only outside the
+	// first Search rank last
+	service.SortRank: {
+		expr: "IFNULL(fts.rank, 0)",
+	},
+}
+
+type sortKey struct {
+	sortColumn
+	field service.SortField
+	desc  bool
+}
+
+// sortKeys resolves sort against sortColumns, appending t.id unless sort
+// already orders by it. Rank needs a Search in f.
+func sortKeys(sort service.Sort, f *TodoFilter) ([]sortKey, error) {
+	keys := make([]sortKey, 0, len(sort)+1)
+	byID := false
+	for _, key := range sort {
+		col, ok := sortColumns[key.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", service.ErrInvalidSort, key.Field)
+		}
+		if key.Field == service.SortID {
+			byID = true
+		}
+		if key.Field == service.SortRank {
+			if !ftsEnabled {
+				return nil, fmt.Errorf("%w: search ranking needs FTS5", service.ErrUnsupported)
+			}
+			if f.search == "" {
+				return nil, fmt.Errorf("%w: %s without search", service.ErrInvalidSort, key.Field)
+			}
+		}
+		keys = append(keys, sortKey{sortColumn: col, field: key.Field, desc: key.Desc})
+	}
+	if !byID {
+		keys = append(keys, sortKey{sortColumn: sortColumns[service.SortID], field: service.SortID})
+	}
+	return keys, nil
+}
+
+// orderBy builds the ORDER BY clause for keys.
+func orderBy(keys []sortKey) service.QueryBuilder {
+	var qry service.QueryBuilder = &QueryBuilder{prefix: "ORDER BY ", sep: ", "}
+	for _, key := range keys {
+		if key.desc {
+			qry.AddText(key.expr + " DESC")
+		} else {
+			qry.AddText(key.expr + " ASC")
+		}
+	}
+	return qry
+}
+
+// keysetAfter builds the condition selecting rows ordered after values:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys.
+func keysetAfter(keys []sortKey, values []any) service.QueryBuilder {
+	var qry service.QueryBuilder = &QueryBuilder{prefix: "(", sep: " OR ", suffix: ")"}
+	for i, key := range keys {
+		var term service.QueryBuilder = &QueryBuilder{prefix: "(", sep: " AND ", suffix: ")"}
+		for j := 0; j < i; j++ {
+			term.AddTextParam(keys[j].expr+" = ?", values[j])
+		}
+		if key.desc {
+			term.AddTextParam(key.expr+" < ?", values[i])
+		} else {
+			term.AddTextParam(key.expr+" > ?", values[i])
+		}
+		qry.AddQuery(term)
+	}
+	return qry
+}
+
+// cursorSort is how keys are recorded in a cursor, "-" marking descending.
+func cursorSort(keys []sortKey) []string {
+	sort := make([]string, len(keys))
+	for i, key := range keys {
+		if key.desc {
+			sort[i] = "-" + string(key.field)
+		} else {
+			sort[i] = string(key.field)
+		}
+	}
+	return sort
+}
+
+func parseCursorInt(v any) (any, error) {
+	if n, ok := v.(json.Number); ok {
+		return n.Int64()
+	}
+	return nil, fmt.Errorf("%w: expected number, got %T", service.ErrInvalidCursor, v)
+}
+
+func parseCursorString(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: expected string, got %T", service.ErrInvalidCursor, v)
+}
+
+func parseCursorBool(v any) (any, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: expected bool, got %T", service.ErrInvalidCursor, v)
+}
+
+func parseCursorTime(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: expected time, got %v", service.ErrInvalidCursor, v)
+}
+
+// parseCursorTimeOr reads a time, or null, which a sort expression uses in
+// place of NULL.
+func parseCursorTimeOr(null string) func(any) (any, error) {
+	return func(v any) (any, error) {
+		if v == null {
+			return null, nil
+		}
+		return parseCursorTime(v)
+	}
+}
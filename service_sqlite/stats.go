@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// statsGroup is the key of a group and the category id it is grouped by,
+// empty to group by key alone.
+type statsGroup struct {
+	key        string
+	categoryID string
+}
+
+// statsGroups is the whitelist of expressions Stats may group by.
+var statsGroups = map[service.StatsGroup]statsGroup{
+	service.StatsByCategory: {key: "category.name", categoryID: "category.id"},
+	service.StatsByDueDate:  {key: "IFNULL(date(t.due_at), '')"},
+	// completed_at is NULL for open todos
+	service.StatsByCompletedDate: {key: "IFNULL(date(t.completed_at), '')"},
+}
+
+// Stats implements service.TodoService.
+func (TodoService) Stats(
+	ctx context.Context, filter service.TodoFilter, groupBy service.StatsGroup,
+) ([]service.TodoStats, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		f, err := compileFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		group := statsGroup{key: "''"}
+		if groupBy != service.StatsAll {
+			if group, ok = statsGroups[groupBy]; !ok {
+				return nil, fmt.Errorf("%w: unknown group %q", service.ErrInvalidGroup, groupBy)
+			}
+		}
+		categoryID, by, order := "0", group.key, group.key
+		if group.categoryID != "" {
+			categoryID, by, order = group.categoryID, group.categoryID, group.key+", "+group.categoryID
+		}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddText("SELECT " + group.key + ", " + categoryID + ", COUNT(t.id), IFNULL(SUM(t.done), 0)")
+		qry.AddQuery(f.from())
+		qry.AddQuery(f.where())
+		if groupBy != service.StatsAll {
+			qry.AddText("GROUP BY " + by + " ORDER BY " + order)
+		}
+		qSql := qry.SQL()
+		qParams := qry.Params()
+		slog.Debug("TodoService.Stats", "qry", qSql, "params", qParams)
+		rows, err := db.QueryContext(ctx, qSql, qParams...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		stats := []service.TodoStats{}
+		for rows.Next() {
+			var st service.TodoStats
+			if err := rows.Scan(&st.Key, &st.CategoryID, &st.Total, &st.Done); err != nil {
+				return nil, err
+			}
+			st.Open = st.Total - st.Done
+			stats = append(stats, st)
+		}
+		return stats, rows.Err()
+	} else {
+		return nil, service.ErrNoDBInContext
+	}
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// CreateTag implements service.TodoService.
+func (TodoService) CreateTag(ctx context.Context, tags []service.Tag) ([]int64, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		stmt, err := tx.PrepareContext(ctx, "INSERT INTO tag (name) VALUES (?)")
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, len(tags))
+		for i, tag := range tags {
+			res, err := stmt.ExecContext(ctx, tag.Name)
+			if err != nil {
+				return nil, err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = id
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		return ids, nil
+	} else {
+		return nil, service.ErrNoDBInContext
+	}
+}
+
+// UpdateTag implements service.TodoService.
+func (TodoService) UpdateTag(ctx context.Context, tags []service.Tag) error {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		stmt, err := tx.PrepareContext(ctx, "UPDATE tag SET name = ? WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			res, err := stmt.ExecContext(ctx, tag.Name, tag.ID)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return fmt.Errorf("%w: tag %d", service.ErrNoData, tag.ID)
+			}
+		}
+		return tx.Commit()
+	} else {
+		return service.ErrNoDBInContext
+	}
+}
+
+// DeleteTag implements service.TodoService.
+func (TodoService) DeleteTag(ctx context.Context, ids []int64) error {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		if len(ids) == 0 {
+			return nil
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := checkIDs(ctx, tx, "tag", "tag", ids); err != nil {
+			return err
+		}
+		inID := inIDs("id", ids)
+		// the todo_tag_tag_delete trigger unlinks the todos
+		_, err = tx.ExecContext(ctx, "DELETE FROM tag WHERE "+inID.SQL(), inID.Params()...)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	} else {
+		return service.ErrNoDBInContext
+	}
+}
+
+// ListTags implements service.TodoService.
+func (TodoService) ListTags(ctx context.Context) ([]service.Tag, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		rows, err := db.QueryContext(ctx, "SELECT id, name FROM tag ORDER BY name")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		tags := []service.Tag{}
+		for rows.Next() {
+			var tag service.Tag
+			if err := rows.Scan(&tag.ID, &tag.Name); err != nil {
+				return nil, err
+			}
+			tags = append(tags, tag)
+		}
+		return tags, rows.Err()
+	} else {
+		return nil, service.ErrNoDBInContext
+	}
+}
+
+// loadTags fills the Tags of todos with a single query. Todos without tags
+// keep nil Tags.
+func loadTags(ctx context.Context, db *sql.DB, todos []service.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(todos))
+	ids := make([]int64, len(todos))
+	for i, todo := range todos {
+		index[todo.ID] = i
+		ids[i] = todo.ID
+	}
+	inTodo := inIDs("tt.todo_id", ids)
+	qSql := "SELECT tt.todo_id, tag.id, tag.name FROM todo_tag tt JOIN tag ON tag.id = tt.tag_id WHERE " +
+		inTodo.SQL() + " ORDER BY tag.name"
+	qParams := inTodo.Params()
+	slog.Debug("TodoService.loadTags", "qry", qSql, "params", qParams)
+	rows, err := db.QueryContext(ctx, qSql, qParams...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoID int64
+		var tag service.Tag
+		if err := rows.Scan(&todoID, &tag.ID, &tag.Name); err != nil {
+			return err
+		}
+		todo := &todos[index[todoID]]
+		todo.Tags = append(todo.Tags, tag)
+	}
+	return rows.Err()
+}
+
+// saveTags links todo id to tags, which must exist; the tags are matched by
+// ID.
+func saveTags(ctx context.Context, stmt *sql.Stmt, id int64, tags []service.Tag) error {
+	tagIDs := make([]int64, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+	slices.Sort(tagIDs)
+	for _, tagID := range slices.Compact(tagIDs) {
+		res, err := stmt.ExecContext(ctx, id, tagID)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("%w: tag %d", service.ErrNoData, tagID)
+		}
+	}
+	return nil
+}
+
+// insertTodoTag is the statement saveTags runs for every tag.
+const insertTodoTag = "INSERT INTO todo_tag (todo_id, tag_id) SELECT ?, id FROM tag WHERE id = ?"
@@ -3,12 +3,24 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"io/fs"
 	"log/slog"
+	"time"
 
 	service "github.com/senomas/gotodo_service"
 )
 
-type TodoService struct{}
+type TodoService struct {
+	// MigrationFS overrides the migration files used by Migrate and Rollback.
+	MigrationFS fs.FS
+	// CursorKey signs the cursors of FindPage. When nil a random key is made
+	// per process, so cursors do not survive a restart.
+	CursorKey []byte
+	// MigrationLockTimeout is how long Migrate and Rollback wait for another
+	// process to release the migration lock, DefaultMigrationLockTimeout when
+	// zero.
+	MigrationLockTimeout time.Duration
+}
 
 // Create implements service.TodoService.
 func (TodoService) Create(ctx context.Context, todos []service.Todo) ([]int64, error) {
@@ -19,13 +31,30 @@ func (TodoService) Create(ctx context.Context, todos []service.Todo) ([]int64, e
 		}
 		defer tx.Rollback()
 
-		stmt, err := tx.PrepareContext(ctx, "INSERT INTO todo (title, description, category_id, done) VALUES (?, ?, ?, ?)")
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO todo
+      (title, description, category_id, parent_id, priority, done, due_at, start_at, created_at, updated_at, completed_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return nil, err
+		}
+		stmtTag, err := tx.PrepareContext(ctx, insertTodoTag)
 		if err != nil {
 			return nil, err
 		}
+		now := time.Now().UTC()
 		ids := make([]int64, len(todos))
 		for i, todo := range todos {
-			res, err := stmt.ExecContext(ctx, todo.Title, todo.Description, todo.Category.ID, todo.Done)
+			var completedAt any
+			if todo.Done {
+				completedAt = now
+			}
+			if err := checkParent(ctx, tx, 0, todo.ParentID); err != nil {
+				return nil, err
+			}
+			res, err := stmt.ExecContext(
+				ctx, todo.Title, todo.Description, todo.Category.ID, todo.ParentID, todo.Priority, todo.Done,
+				nullTime(todo.DueAt), nullTime(todo.StartAt), now, now, completedAt,
+			)
 			if err != nil {
 				return nil, err
 			}
@@ -33,6 +62,9 @@ func (TodoService) Create(ctx context.Context, todos []service.Todo) ([]int64, e
 			if err != nil {
 				return nil, err
 			}
+			if err := saveTags(ctx, stmtTag, id, todo.Tags); err != nil {
+				return nil, err
+			}
 			ids[i] = id
 		}
 
@@ -55,13 +87,31 @@ func (TodoService) Update(ctx context.Context, todos []service.Todo) (int64, err
 		}
 		defer tx.Rollback()
 
-		stmt, err := tx.PrepareContext(ctx, "UPDATE todo SET title = ?, description = ?, category_id = ?, done = ? WHERE id = ?")
+		stmt, err := tx.PrepareContext(ctx, `UPDATE todo SET
+      title = ?, description = ?, category_id = ?, parent_id = ?, priority = ?, due_at = ?, start_at = ?, updated_at = ?,
+      `+setCompletedAt+`, done = ?
+      WHERE id = ?`)
+		if err != nil {
+			return 0, err
+		}
+		stmtUntag, err := tx.PrepareContext(ctx, "DELETE FROM todo_tag WHERE todo_id = ?")
+		if err != nil {
+			return 0, err
+		}
+		stmtTag, err := tx.PrepareContext(ctx, insertTodoTag)
 		if err != nil {
 			return 0, err
 		}
+		now := time.Now().UTC()
 		var affected int64
 		for _, todo := range todos {
-			res, err := stmt.ExecContext(ctx, todo.Title, todo.Description, todo.Category.ID, todo.Done, todo.ID)
+			if err := checkParent(ctx, tx, todo.ID, todo.ParentID); err != nil {
+				return 0, err
+			}
+			res, err := stmt.ExecContext(
+				ctx, todo.Title, todo.Description, todo.Category.ID, todo.ParentID, todo.Priority, nullTime(todo.DueAt), nullTime(todo.StartAt),
+				now, todo.Done, now, now, todo.Done, todo.ID,
+			)
 			if err != nil {
 				return 0, err
 			}
@@ -69,6 +119,14 @@ func (TodoService) Update(ctx context.Context, todos []service.Todo) (int64, err
 			if err != nil {
 				return 0, err
 			}
+			if aff > 0 {
+				if _, err := stmtUntag.ExecContext(ctx, todo.ID); err != nil {
+					return 0, err
+				}
+				if err := saveTags(ctx, stmtTag, todo.ID, todo.Tags); err != nil {
+					return 0, err
+				}
+			}
 			affected += aff
 		}
 
@@ -85,8 +143,29 @@ func (TodoService) Update(ctx context.Context, todos []service.Todo) (int64, err
 // Delete implements service.TodoService.
 func (TodoService) Delete(ctx context.Context, ids []int64) error {
 	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
-		slog.Debug("TodoService.Delete", "db", db)
-		panic("unimplemented")
+		if len(ids) == 0 {
+			return nil
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := checkIDs(ctx, tx, "todo", "todo", ids); err != nil {
+			return err
+		}
+		stmt, err := tx.PrepareContext(ctx, "DELETE FROM todo WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if _, err := stmt.ExecContext(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit()
 	} else {
 		return service.ErrNoDBInContext
 	}
@@ -94,18 +173,19 @@ func (TodoService) Delete(ctx context.Context, ids []int64) error {
 
 // Find implements service.TodoService.
 func (TodoService) Find(
-	ctx context.Context, filter service.TodoFilter, offset int64, limit int,
+	ctx context.Context, filter service.TodoFilter, sort service.Sort, offset int64, limit int,
 ) (int64, []service.Todo, error) {
 	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
-		var qryFrom service.QueryBuilder = &QueryBuilder{sep: " "}
-		qryFrom.AddText("FROM todo t JOIN todo_category category ON t.category_id = category.id")
-		var qryWhere service.QueryBuilder = &QueryBuilder{prefix: "WHERE ", sep: " AND "}
-		if f, ok := filter.(*TodoFilter); ok {
-			f.Generate(qryWhere)
-		} else if filter != nil {
-			slog.Error("TodoService.Find invalid filter", "filter", filter)
-			return 0, nil, service.ErrInvalidFilter
+		f, err := compileFilter(filter)
+		if err != nil {
+			return 0, nil, err
 		}
+		keys, err := sortKeys(sort, f)
+		if err != nil {
+			return 0, nil, err
+		}
+		qryFrom := f.from()
+		qryWhere := f.where()
 		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
 		qry.AddText("SELECT COUNT(t.id)")
 		qry.AddQuery(qryFrom)
@@ -126,9 +206,10 @@ func (TodoService) Find(
 			}
 		}
 		qry = &QueryBuilder{sep: " "}
-		qry.AddText("SELECT t.id, title, description, category_id, category.name, done")
+		qry.AddText("SELECT " + f.columns())
 		qry.AddQuery(qryFrom)
 		qry.AddQuery(qryWhere)
+		qry.AddQuery(orderBy(keys))
 		qry.AddTextParams("LIMIT ? OFFSET ?", limit, offset)
 		qSql = qry.SQL()
 		qParams = qry.Params()
@@ -138,38 +219,67 @@ func (TodoService) Find(
 			return total, nil, err
 		}
 		defer rows.Close()
-		var todos []service.Todo
-		for rows.Next() {
-			var todo service.Todo
-			err = rows.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Category.ID, &todo.Category.Name, &todo.Done)
-			if err != nil {
-				return total, nil, err
-			}
-			todos = append(todos, todo)
+		todos, err := scanTodos(rows)
+		if err != nil {
+			return total, nil, err
 		}
-		return total, todos, nil
+		return total, todos, loadTags(ctx, db, todos)
 	} else {
 		return 0, nil, service.ErrNoDBInContext
 	}
 }
 
+const todoColumns = "t.id, t.title, t.description, t.category_id, category.name, t.done, t.due_at, t.start_at, " +
+	"t.created_at, t.updated_at, t.completed_at, t.priority, t.parent_id"
+
+// setCompletedAt sets completed_at when done becomes true and clears it when
+// done becomes false. Its parameters are the new done and now, twice.
+const setCompletedAt = "completed_at = CASE WHEN NOT ? THEN NULL WHEN done THEN IFNULL(completed_at, ?) ELSE ? END"
+
+// scanTodos reads rows selected with TodoFilter.columns.
+func scanTodos(rows *sql.Rows) ([]service.Todo, error) {
+	var todos []service.Todo
+	for rows.Next() {
+		var todo service.Todo
+		var snippet sql.NullString
+		err := rows.Scan(
+			&todo.ID, &todo.Title, &todo.Description, &todo.Category.ID, &todo.Category.Name, &todo.Done,
+			&todo.DueAt, &todo.StartAt, &todo.CreatedAt, &todo.UpdatedAt, &todo.CompletedAt, &todo.Priority,
+			&todo.ParentID, &snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		todo.Snippet = snippet.String
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
 // Get implements service.TodoService.
 func (TodoService) Get(ctx context.Context, id int64) (service.Todo, error) {
 	var todo service.Todo
 	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
-		rows, err := db.QueryContext(ctx, `
-      SELECT t.id, title, description, category_id, c.name, done 
-      FROM todo t JOIN todo_category c ON t.category_id = c.id 
-      WHERE t.id = ?
-    `, id)
+		f := &TodoFilter{}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddText("SELECT " + f.columns())
+		qry.AddQuery(f.from())
+		qry.AddTextParam("WHERE t.id = ?", id)
+		rows, err := db.QueryContext(ctx, qry.SQL(), qry.Params()...)
 		if err != nil {
 			return todo, err
 		}
 		defer rows.Close()
-		if rows.Next() {
-			err = rows.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Category.ID, &todo.Category.Name, &todo.Done)
+		todos, err := scanTodos(rows)
+		if err != nil {
 			return todo, err
 		}
+		if len(todos) > 0 {
+			if err := loadTags(ctx, db, todos); err != nil {
+				return todo, err
+			}
+			return todos[0], nil
+		}
 		return todo, service.ErrNoData
 	} else {
 		return todo, service.ErrNoDBInContext
@@ -3,6 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"slices"
+	"time"
 
 	service "github.com/senomas/gotodo_service"
 )
@@ -43,11 +46,134 @@ func (TodoService) CreateCategory(ctx context.Context, categories []service.Todo
 }
 
 // DeleteCategory implements service.TodoService.
-func (TodoService) DeleteCategory(ctx context.Context, ids []int64) error {
-	panic("unimplemented")
+func (TodoService) DeleteCategory(
+	ctx context.Context, ids []int64, policy service.DeletePolicy, reassignTo int64,
+) error {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		if len(ids) == 0 {
+			return nil
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := checkIDs(ctx, tx, "todo_category", "category", ids); err != nil {
+			return err
+		}
+		inCategory := inIDs("category_id", ids)
+		switch policy {
+		case service.DeleteRestrict:
+			var count int64
+			err = tx.QueryRowContext(ctx, "SELECT COUNT(id) FROM todo WHERE "+inCategory.SQL(), inCategory.Params()...).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return &service.CategoryInUseError{Todos: count}
+			}
+		case service.DeleteCascade:
+			_, err = tx.ExecContext(ctx, "DELETE FROM todo WHERE "+inCategory.SQL(), inCategory.Params()...)
+			if err != nil {
+				return err
+			}
+		case service.DeleteReassign:
+			if slices.Contains(ids, reassignTo) {
+				return fmt.Errorf("cannot reassign todos to deleted category %d", reassignTo)
+			}
+			var exists bool
+			err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM todo_category WHERE id = ?)", reassignTo).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("%w: category %d", service.ErrNoData, reassignTo)
+			}
+			params := append([]any{reassignTo, time.Now().UTC()}, inCategory.Params()...)
+			_, err = tx.ExecContext(ctx, "UPDATE todo SET category_id = ?, updated_at = ? WHERE "+inCategory.SQL(), params...)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown delete policy %d", policy)
+		}
+		inID := inIDs("id", ids)
+		_, err = tx.ExecContext(ctx, "DELETE FROM todo_category WHERE "+inID.SQL(), inID.Params()...)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	} else {
+		return service.ErrNoDBInContext
+	}
 }
 
 // UpdateCategory implements service.TodoService.
 func (TodoService) UpdateCategory(ctx context.Context, categories []service.TodoCategory) error {
-	panic("unimplemented")
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		stmt, err := tx.PrepareContext(ctx, "UPDATE todo_category SET name = ? WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			res, err := stmt.ExecContext(ctx, category.Name, category.ID)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return fmt.Errorf("%w: category %d", service.ErrNoData, category.ID)
+			}
+		}
+		return tx.Commit()
+	} else {
+		return service.ErrNoDBInContext
+	}
+}
+
+// inIDs builds "column IN (?, ...)" for ids.
+func inIDs(column string, ids []int64) service.QueryBuilder {
+	var qry service.QueryBuilder = &QueryBuilder{prefix: column + " IN (", sep: ", ", suffix: ")"}
+	for _, id := range ids {
+		qry.AddTextParam("?", id)
+	}
+	return qry
+}
+
+// checkIDs fails with service.ErrNoData naming the first of ids missing from
+// table, a name it is reported as. It runs before deleting, as cascades may
+// remove later ids first.
+func checkIDs(ctx context.Context, tx *sql.Tx, table, name string, ids []int64) error {
+	inID := inIDs("id", ids)
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM "+table+" WHERE "+inID.SQL(), inID.Params()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	found := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("%w: %s %d", service.ErrNoData, name, id)
+		}
+	}
+	return nil
 }
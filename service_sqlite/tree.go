@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	service "github.com/senomas/gotodo_service"
+)
+
+// subtree selects the ids of todo ? and all its subtasks.
+const subtree = `WITH RECURSIVE tree(id) AS (
+      SELECT id FROM todo WHERE id = ?
+      UNION SELECT t.id FROM todo t JOIN tree ON t.parent_id = tree.id
+    )`
+
+// checkParent fails when parentID does not exist or would make todo id its
+// own ancestor; id is 0 for a new todo.
+func checkParent(ctx context.Context, tx *sql.Tx, id int64, parentID sql.NullInt64) error {
+	if !parentID.Valid {
+		return nil
+	}
+	var exists, cycle bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM todo WHERE id = ?),
+      EXISTS (`+subtree+` SELECT 1 FROM tree WHERE id = ?)`, parentID.Int64, id, parentID.Int64,
+	).Scan(&exists, &cycle)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: parent %d", service.ErrNoData, parentID.Int64)
+	}
+	if cycle {
+		return fmt.Errorf("%w: todo %d under %d", service.ErrParentCycle, id, parentID.Int64)
+	}
+	return nil
+}
+
+// Children implements service.TodoService.
+func (TodoService) Children(ctx context.Context, id int64) ([]service.Todo, error) {
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		f := &TodoFilter{}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddText("SELECT " + f.columns())
+		qry.AddQuery(f.from())
+		qry.AddTextParam("WHERE t.parent_id = ? ORDER BY t.id", id)
+		return queryTodos(ctx, db, "TodoService.Children", qry)
+	} else {
+		return nil, service.ErrNoDBInContext
+	}
+}
+
+// Tree implements service.TodoService.
+func (TodoService) Tree(ctx context.Context, rootID int64) (service.TodoNode, error) {
+	var node service.TodoNode
+	if db, ok := ctx.Value(service.ServiceContextDB).(*sql.DB); ok {
+		f := &TodoFilter{}
+		var qry service.QueryBuilder = &QueryBuilder{sep: " "}
+		qry.AddTextParam(subtree, rootID)
+		qry.AddText("SELECT " + f.columns())
+		qry.AddQuery(f.from())
+		qry.AddText("JOIN tree ON tree.id = t.id ORDER BY t.id")
+		todos, err := queryTodos(ctx, db, "TodoService.Tree", qry)
+		if err != nil {
+			return node, err
+		}
+		root := -1
+		children := map[int64][]int{}
+		for i, todo := range todos {
+			if todo.ID == rootID {
+				root = i
+			} else {
+				children[todo.ParentID.Int64] = append(children[todo.ParentID.Int64], i)
+			}
+		}
+		if root < 0 {
+			return node, service.ErrNoData
+		}
+		return buildNode(todos, children, root), nil
+	} else {
+		return node, service.ErrNoDBInContext
+	}
+}
+
+// buildNode builds the node of todos[i], rolling up the progress of its
+// subtasks.
+func buildNode(todos []service.Todo, children map[int64][]int, i int) service.TodoNode {
+	node := service.TodoNode{Todo: todos[i]}
+	for _, c := range children[todos[i].ID] {
+		child := buildNode(todos, children, c)
+		node.Progress.Total += child.Progress.Total + 1
+		node.Progress.Done += child.Progress.Done
+		if child.Todo.Done {
+			node.Progress.Done++
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node
+}
+
+// queryTodos runs qry selecting TodoFilter.columns and loads the tags of the
+// todos.
+func queryTodos(ctx context.Context, db *sql.DB, name string, qry service.QueryBuilder) ([]service.Todo, error) {
+	qSql := qry.SQL()
+	qParams := qry.Params()
+	slog.Debug(name, "qry", qSql, "params", qParams)
+	rows, err := db.QueryContext(ctx, qSql, qParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+	return todos, loadTags(ctx, db, todos)
+}